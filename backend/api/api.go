@@ -5,9 +5,11 @@ import (
 	"net/http"
 
 	"github.com/HexCardGames/HexDeck/db"
+	"github.com/HexCardGames/HexDeck/decks"
 	"github.com/HexCardGames/HexDeck/game"
 	"github.com/HexCardGames/HexDeck/types"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type ErrorReply struct {
@@ -22,12 +24,20 @@ type StatsReply struct {
 type ImprintReply struct {
 	Content string
 }
+type CardDeckReply struct {
+	CardDeckId int
+	Name       string
+}
 type CreateRoomReply struct {
 	JoinCode string
 }
+type CreateRoomRequest struct {
+	Username string
+}
 type JoinRoomRequest struct {
 	JoinCode string
 	Username string
+	Spectate bool
 }
 type LeaveRoomRequest struct {
 	SessionToken string
@@ -49,12 +59,26 @@ func RegisterApi(server *gin.Engine) {
 		})
 	})
 
+	server.GET("/api/decks", func(c *gin.Context) {
+		definitions := decks.ListDecks()
+		reply := make([]CardDeckReply, len(definitions))
+		for i, definition := range definitions {
+			reply[i] = CardDeckReply{CardDeckId: definition.Id, Name: definition.Name}
+		}
+		c.JSON(http.StatusOK, reply)
+	})
+
+	server.GET("/api/permissions", func(c *gin.Context) {
+		c.JSON(http.StatusOK, types.PermissionMatrix)
+	})
+
 	server.POST("/api/room/create", func(c *gin.Context) {
-		request := JoinRoomRequest{}
+		request := CreateRoomRequest{}
 		c.BindJSON(&request)
 		room := game.CreateRoom()
 		player := game.JoinRoom(room, request.Username)
 		player.SetPermissionBit(types.PermissionHost)
+		room.OwnerId = player.PlayerId
 		slog.Debug("New room created", "username", player.Username, "sessionToken", player.SessionToken, "roomId", room.RoomId.Hex())
 		c.JSON(http.StatusOK, player)
 	})
@@ -71,6 +95,12 @@ func RegisterApi(server *gin.Engine) {
 			})
 			return
 		}
+		if request.Spectate {
+			spectator := game.SpectateRoom(room, request.Username)
+			slog.Debug("New spectator session created", "username", spectator.Username, "sessionToken", spectator.SessionToken, "roomId", room.RoomId.Hex(), "joinCode", request.JoinCode)
+			c.JSON(http.StatusOK, spectator)
+			return
+		}
 		if room.GameState != types.StateLobby {
 			slog.Debug("Client tried joining room not in lobby state", "joinCode", request.JoinCode)
 			c.JSON(http.StatusBadRequest, ErrorReply{
@@ -127,11 +157,42 @@ func RegisterApi(server *gin.Engine) {
 			})
 			return
 		}
-		room.RemovePlayer(*player)
+		if room.RemovePlayer(*player) {
+			game.LogEvent(room, types.EventPlayerLeft, player, nil)
+		}
 		game.OnRoomUpdate(room)
 		c.Status(http.StatusOK)
 	})
 
+	server.GET("/api/room/:roomId/events", func(c *gin.Context) {
+		roomId, err := bson.ObjectIDFromHex(c.Param("roomId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorReply{
+				StatusCode: "invalid_room_id",
+				Message:    "No valid roomId was provided",
+			})
+			return
+		}
+		// The log contains every hand and drawn card, so it is only served
+		// once the game has ended
+		if room := game.FindRoomById(roomId); room != nil && room.GameState != types.StateEnded {
+			c.JSON(http.StatusForbidden, ErrorReply{
+				StatusCode: "game_not_ended",
+				Message:    "The event log is only available after the game has ended",
+			})
+			return
+		}
+		events, ok := db.Conn.QueryEvents(roomId)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, ErrorReply{
+				StatusCode: "internal_error",
+				Message:    "Loading the event log failed",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, events)
+	})
+
 	// Handle WebSocket connections using Socket.io
 	wsHandler := initWS()
 	server.Any("/socket.io/", gin.WrapH(wsHandler))
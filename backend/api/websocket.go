@@ -62,29 +62,39 @@ func unpackData(datas []any, target interface{}) bool {
 	return ok != nil
 }
 
-func verifyPlayerIsActivePlayer(room *types.Room, target *types.Player) bool {
-	if target.Connection.Socket == nil {
-		return false
+func emitStatus(client *socketio.Socket, status *types.S2C_Status) {
+	if status == nil {
+		return
 	}
+	client.Emit("Status", *status)
+}
 
-	if room.GameState != types.StateRunning {
-		target.Connection.Socket.Emit("Status", types.S2C_Status{
+// verifyPermissionChange checks that the granter holds every permission bit
+// being granted or revoked and that the owner's permissions stay untouched
+func verifyPermissionChange(room *types.Room, granter *types.Player, target *types.Player, permissions int) *types.S2C_Status {
+	if !types.IsValidPermissionMask(permissions) {
+		return &types.S2C_Status{
 			IsError:    true,
-			StatusCode: "game_not_running",
-			Message:    "The game is not running",
-		})
-		return false
+			StatusCode: "invalid_permissions",
+			Message:    "The requested permissions are not valid",
+		}
 	}
-
-	if !room.CardDeck.IsPlayerActive(target) {
-		target.Connection.Socket.Emit("Status", types.S2C_Status{
+	if room.IsOwner(target) && granter != target {
+		return &types.S2C_Status{
 			IsError:    true,
-			StatusCode: "player_not_active",
-			Message:    "You can't execute this action while you are not the active player",
-		})
-		return false
+			StatusCode: "insufficient_permission",
+			Message:    "You can't change the permissions of the room owner",
+		}
+	}
+	changed := target.Permissions ^ permissions
+	if changed&^room.GetPermissions(granter) != 0 {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "insufficient_permission",
+			Message:    "You can't grant or revoke permissions you don't hold",
+		}
 	}
-	return true
+	return nil
 }
 
 func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Player) {
@@ -95,6 +105,12 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 		game.OnRoomUpdate(room)
 	})
 
+	// Spectators only receive updates and can't send any room or game actions
+	if room.IsSpectator(player) {
+		game.SendInitialData(room, player)
+		return
+	}
+
 	client.On("SetCardDeck", func(datas ...any) {
 		setCardDeckRequest := types.C2S_SetCardDeck{}
 		unpackData(datas, &setCardDeckRequest)
@@ -107,11 +123,11 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 			})
 			return
 		}
-		if !player.HasPermissionBit(types.PermissionHost) {
+		if !room.HasPermission(player, types.PermissionChangeDeck) {
 			client.Emit("Status", types.S2C_Status{
 				IsError:    true,
 				StatusCode: "insufficient_permission",
-				Message:    "You can't change the card deck unless you are host",
+				Message:    "You don't have permission to change the card deck",
 			})
 			return
 		}
@@ -125,13 +141,73 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 		}
 	})
 
+	client.On("SetMoveTimeout", func(datas ...any) {
+		setMoveTimeoutRequest := types.C2S_SetMoveTimeout{}
+		unpackData(datas, &setMoveTimeoutRequest)
+
+		if room.GameState != types.StateLobby {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "game_already_running",
+				Message:    "You can't change the move timeout while the game is running",
+			})
+			return
+		}
+		if !room.HasPermission(player, types.PermissionChangeOptions) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "insufficient_permission",
+				Message:    "You don't have permission to change the move timeout",
+			})
+			return
+		}
+		if !game.SetMoveTimeout(room, setMoveTimeoutRequest.MoveTimeout) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "invalid_move_timeout",
+				Message:    "The move timeout must be 0 or between 5 seconds and 10 minutes",
+			})
+			return
+		}
+	})
+
+	client.On("UpdateGameOptions", func(datas ...any) {
+		updateGameOptionsRequest := types.C2S_UpdateGameOptions{}
+		unpackData(datas, &updateGameOptionsRequest)
+
+		if room.GameState != types.StateLobby {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "game_already_running",
+				Message:    "You can't change the game options while the game is running",
+			})
+			return
+		}
+		if !room.HasPermission(player, types.PermissionChangeOptions) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "insufficient_permission",
+				Message:    "You don't have permission to change the game options",
+			})
+			return
+		}
+		if !game.SetGameOptions(room, updateGameOptionsRequest.GameOptions) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "invalid_game_options",
+				Message:    "The provided game options are not valid",
+			})
+			return
+		}
+	})
+
 	client.On("UpdatePlayer", func(datas ...any) {
 		player.Mutex.Lock()
 		defer player.Mutex.Unlock()
 
 		updatePlayerRequest := types.C2S_UpdatePlayer{}
 		unpackData(datas, &updatePlayerRequest)
-		if updatePlayerRequest.PlayerId != player.PlayerId && !player.HasPermissionBit(types.PermissionHost) {
+		if updatePlayerRequest.PlayerId != player.PlayerId && !room.HasPermission(player, types.PermissionHost) {
 			client.Emit("Status", types.S2C_Status{
 				IsError:    true,
 				StatusCode: "insufficient_permission",
@@ -166,7 +242,22 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 			}
 		}
 		if updatePlayerRequest.Permissions != nil {
-			targetPlayer.Permissions = *updatePlayerRequest.Permissions
+			if status := verifyPermissionChange(room, player, targetPlayer, *updatePlayerRequest.Permissions); status != nil {
+				emitStatus(client, status)
+			} else {
+				targetPlayer.Permissions = *updatePlayerRequest.Permissions
+			}
+		}
+		if updatePlayerRequest.Team != nil {
+			if room.GameState != types.StateLobby || !game.IsValidTeam(*updatePlayerRequest.Team) {
+				client.Emit("Status", types.S2C_Status{
+					IsError:    true,
+					StatusCode: "invalid_team",
+					Message:    "Teams can only be changed in the lobby",
+				})
+			} else {
+				targetPlayer.Team = *updatePlayerRequest.Team
+			}
 		}
 
 		game.OnRoomUpdate(room)
@@ -178,15 +269,18 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 
 		kickPlayerRequest := types.C2S_KickPlayer{}
 		unpackData(datas, &kickPlayerRequest)
-		if !player.HasPermissionBit(types.PermissionHost) {
+		if !room.HasPermission(player, types.PermissionKick) {
 			client.Emit("Status", types.S2C_Status{
 				IsError:    true,
 				StatusCode: "insufficient_permission",
-				Message:    "You can't update other users unless you are host",
+				Message:    "You don't have permission to kick players",
 			})
 			return
 		}
 		targetPlayer := room.FindPlayer(kickPlayerRequest.PlayerId)
+		if targetPlayer == nil {
+			targetPlayer = room.FindSpectator(kickPlayerRequest.PlayerId)
+		}
 		if targetPlayer == nil {
 			client.Emit("Status", types.S2C_Status{
 				IsError:    true,
@@ -196,11 +290,21 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 			return
 		}
 
+		if player != targetPlayer && (room.IsOwner(targetPlayer) || room.GetPermissions(targetPlayer)&^room.GetPermissions(player) != 0) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "insufficient_permission",
+				Message:    "You can't kick players holding permissions you don't have",
+			})
+			return
+		}
+
 		if player == targetPlayer {
 			player.Mutex.Unlock()
 		}
 		if room.RemovePlayer(*targetPlayer) {
 			slog.Debug("Player was kicked from room", "playerId", player.PlayerId, "targetPlayerId", kickPlayerRequest.PlayerId, "roomId", room.RoomId)
+			game.LogEvent(room, types.EventPlayerKicked, targetPlayer, types.EventDataPlayerKicked{KickedBy: player.PlayerId})
 			if targetPlayer.Connection.IsConnected && targetPlayer.Connection.Socket != nil {
 				targetPlayer.Connection.Socket.Emit("Status", types.S2C_Status{
 					IsError:    true,
@@ -216,12 +320,33 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 		game.OnRoomUpdate(room)
 	})
 
+	client.On("AddBot", func(datas ...any) {
+		if room.GameState != types.StateLobby {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "game_already_running",
+				Message:    "You can't add bots while the game is running",
+			})
+			return
+		}
+		if !room.HasPermission(player, types.PermissionHost) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "insufficient_permission",
+				Message:    "You can't add bots unless you are host",
+			})
+			return
+		}
+		bot := game.AddBot(room)
+		slog.Debug("Bot was added to room", "playerId", player.PlayerId, "botId", bot.PlayerId, "roomId", room.RoomId)
+	})
+
 	client.On("StartGame", func(datas ...any) {
-		if !player.HasPermissionBit(types.PermissionHost) {
+		if !room.HasPermission(player, types.PermissionStartGame) {
 			client.Emit("Status", types.S2C_Status{
 				IsError:    true,
 				StatusCode: "insufficient_permission",
-				Message:    "You can't start the game unless you are host",
+				Message:    "You don't have permission to start the game",
 			})
 			return
 		}
@@ -233,31 +358,98 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 			})
 			return
 		}
+		if room.GameOptions.TeamMode && !game.ValidateTeams(room) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "invalid_teams",
+				Message:    "Every player has to join a team and all teams need the same size",
+			})
+			return
+		}
 		game.StartGame(room)
 	})
 
-	client.On("DrawCard", func(datas ...any) {
-		player.Mutex.Lock()
-		defer player.Mutex.Unlock()
+	client.On("PauseGame", func(datas ...any) {
+		if !room.HasPermission(player, types.PermissionHost) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "insufficient_permission",
+				Message:    "You can't pause the game unless you are host",
+			})
+			return
+		}
+		if !game.SetPaused(room, true) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "cannot_pause",
+				Message:    "Only a running game that isn't paused can be paused",
+			})
+			return
+		}
+	})
 
-		if !verifyPlayerIsActivePlayer(room, player) {
+	client.On("ResumeGame", func(datas ...any) {
+		if !room.HasPermission(player, types.PermissionHost) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "insufficient_permission",
+				Message:    "You can't resume the game unless you are host",
+			})
 			return
 		}
-		card := room.CardDeck.DrawCard()
-		if card == nil {
-			// TODO: Handle empty card deck
+		if !game.SetPaused(room, false) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "cannot_resume",
+				Message:    "Only a paused game can be resumed",
+			})
 			return
 		}
-		game.UpdateAllPlayers(room)
 	})
 
-	client.On("PlayCard", func(datas ...any) {
+	client.On("Rematch", func(datas ...any) {
+		if !room.HasPermission(player, types.PermissionStartGame) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "insufficient_permission",
+				Message:    "You don't have permission to start a rematch",
+			})
+			return
+		}
+		if !game.Rematch(room) {
+			client.Emit("Status", types.S2C_Status{
+				IsError:    true,
+				StatusCode: "game_not_ended",
+				Message:    "A rematch can only be started after the game has ended",
+			})
+			return
+		}
+	})
+
+	client.On("DrawCard", func(datas ...any) {
 		player.Mutex.Lock()
 		defer player.Mutex.Unlock()
 
-		if !verifyPlayerIsActivePlayer(room, player) {
-			return
-		}
+		emitStatus(client, game.DrawCard(room, player))
+	})
+
+	client.On("PassTurn", func(datas ...any) {
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
+
+		emitStatus(client, game.PassTurn(room, player))
+	})
+
+	client.On("ChallengeDrawFour", func(datas ...any) {
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
+
+		emitStatus(client, game.ChallengeDrawFour(room, player))
+	})
+
+	client.On("PlayCard", func(datas ...any) {
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
 
 		updatePlayerRequest := types.C2S_PlayCard{}
 		unpackData(datas, &updatePlayerRequest)
@@ -269,55 +461,85 @@ func onPlayerJoin(client *socketio.Socket, room *types.Room, player *types.Playe
 			})
 			return
 		}
-		if *updatePlayerRequest.CardIndex < 0 || *updatePlayerRequest.CardIndex >= len(player.Cards) {
+		emitStatus(client, game.PlayCard(room, player, *updatePlayerRequest.CardIndex))
+	})
+
+	client.On("PassCard", func(datas ...any) {
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
+
+		passCardRequest := types.C2S_PassCard{}
+		unpackData(datas, &passCardRequest)
+		if passCardRequest.CardIndex == nil {
 			client.Emit("Status", types.S2C_Status{
 				IsError:    true,
-				StatusCode: "invalid_card_index",
-				Message:    "Provided CardIndex is out of bounds",
+				StatusCode: "missing_parameter",
+				Message:    "CardIndex parameter is missing",
 			})
 			return
 		}
-		card := player.Cards[*updatePlayerRequest.CardIndex]
-		if !room.CardDeck.CanPlay(card) {
+		emitStatus(client, game.PassCardToPartner(room, player, *passCardRequest.CardIndex, passCardRequest.PlayerId))
+	})
+
+	client.On("PlayCards", func(datas ...any) {
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
+
+		playCardsRequest := types.C2S_PlayCards{}
+		unpackData(datas, &playCardsRequest)
+		if len(playCardsRequest.CardIndices) == 0 {
 			client.Emit("Status", types.S2C_Status{
 				IsError:    true,
-				StatusCode: "card_not_playable",
-				Message:    "You can't play this card now",
+				StatusCode: "missing_parameter",
+				Message:    "CardIndices parameter is missing",
 			})
 			return
 		}
-		player.Cards = append(player.Cards[:*updatePlayerRequest.CardIndex], player.Cards[*updatePlayerRequest.CardIndex+1:]...)
-		if !room.CardDeck.PlayCard(card) {
-			slog.Error("Cannot play card after checking", "roomId", room.RoomId.Hex(), "playerId", player.PlayerId.Hex())
-		}
-		game.OnPlayCard(room, player, *updatePlayerRequest.CardIndex, card)
+		emitStatus(client, game.PlayCards(room, player, playCardsRequest.CardIndices))
+	})
 
-		if len(player.Cards) == 0 {
-			room.Winner = &player.PlayerId
-			game.UpdateGameState(room, types.StateEnded)
-		}
+	client.On("ResolveChoice", func(datas ...any) {
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
+
+		resolveChoiceRequest := types.C2S_ResolveChoice{}
+		unpackData(datas, &resolveChoiceRequest)
+		emitStatus(client, game.ResolveChoice(room, player, resolveChoiceRequest.Answer))
 	})
 
+	// Kept for clients that still update the played card directly
 	client.On("UpdatePlayedCard", func(datas ...any) {
 		player.Mutex.Lock()
 		defer player.Mutex.Unlock()
 
-		if !verifyPlayerIsActivePlayer(room, player) {
-			return
-		}
+		updatePlayedCardRequest := types.C2S_UpdatePlayedCard{}
+		unpackData(datas, &updatePlayedCardRequest)
+		emitStatus(client, game.ResolveChoice(room, player, updatePlayedCardRequest.Answer()))
+	})
 
-		updatePlayerRequest := types.C2S_UpdatePlayedCard{}
-		unpackData(datas, &updatePlayerRequest)
-		card := room.CardDeck.UpdatePlayedCard(updatePlayerRequest.CardData)
-		if card == nil {
+	client.On("DeclareLastCard", func(datas ...any) {
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
+
+		emitStatus(client, game.DeclareLastCard(room, player))
+	})
+
+	client.On("CatchPlayer", func(datas ...any) {
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
+
+		catchPlayerRequest := types.C2S_CatchPlayer{}
+		unpackData(datas, &catchPlayerRequest)
+		targetPlayer := room.FindPlayer(catchPlayerRequest.PlayerId)
+		if targetPlayer == nil {
 			client.Emit("Status", types.S2C_Status{
 				IsError:    true,
-				StatusCode: "card_not_updatable",
-				Message:    "You can't update this card now",
+				StatusCode: "invalid_player",
+				Message:    "No player with the requested playerId was found",
 			})
 			return
 		}
-		game.OnPlayedCardUpdate(room, player, card)
+		emitStatus(client, game.CatchPlayer(room, player, targetPlayer))
 	})
 
 	game.SendInitialData(room, player)
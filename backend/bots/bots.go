@@ -0,0 +1,108 @@
+package bots
+
+import (
+	"github.com/HexCardGames/HexDeck/decks"
+	"github.com/HexCardGames/HexDeck/types"
+)
+
+// Strategy decides the moves of a bot player. It only sees what a human
+// player would see on their own screen.
+type Strategy interface {
+	// ChooseCard returns the index of the card to play or -1 to draw a card
+	ChooseCard(hand []types.Card, topCard types.Card, canPlay func(types.Card) bool) int
+	// ChooseColor returns the color a played wildcard should take
+	ChooseColor(hand []types.Card, colors []string) string
+	// ChoosePlayer returns the player to target with a card like a Classic 7
+	ChoosePlayer(self *types.Player, players []*types.Player) *types.Player
+}
+
+// SimpleStrategy plays the first playable card, keeping wildcards for last,
+// picks the color it holds the most cards of and targets the opponent holding
+// the fewest cards
+type SimpleStrategy struct{}
+
+func (strategy SimpleStrategy) ChooseCard(hand []types.Card, topCard types.Card, canPlay func(types.Card) bool) int {
+	wildIndex := -1
+	for i, card := range hand {
+		if !canPlay(card) {
+			continue
+		}
+		if isWild(card) {
+			if wildIndex == -1 {
+				wildIndex = i
+			}
+			continue
+		}
+		return i
+	}
+	return wildIndex
+}
+
+func (strategy SimpleStrategy) ChooseColor(hand []types.Card, colors []string) string {
+	if len(colors) == 0 {
+		return ""
+	}
+	counts := make(map[string]int)
+	for _, card := range hand {
+		counts[cardColor(card)] += 1
+	}
+	bestColor := colors[0]
+	for _, color := range colors {
+		if counts[color] > counts[bestColor] {
+			bestColor = color
+		}
+	}
+	return bestColor
+}
+
+func (strategy SimpleStrategy) ChoosePlayer(self *types.Player, players []*types.Player) *types.Player {
+	var target *types.Player
+	for _, player := range players {
+		if player == self || (self.Team != 0 && player.Team == self.Team) {
+			continue
+		}
+		if target == nil || len(player.Cards) < len(target.Cards) {
+			target = player
+		}
+	}
+	return target
+}
+
+// ChooseAnswer answers a pending choice of the bot using the strategy
+func ChooseAnswer(strategy Strategy, self *types.Player, players []*types.Player, choice *types.PendingChoice) string {
+	if len(choice.Options) == 0 {
+		return ""
+	}
+	switch choice.Kind {
+	case types.ChoiceColor:
+		return strategy.ChooseColor(self.Cards, choice.Options)
+	case types.ChoicePlayer:
+		candidates := make([]*types.Player, 0, len(players))
+		for _, player := range players {
+			if choice.HasOption(player.PlayerId.Hex()) {
+				candidates = append(candidates, player)
+			}
+		}
+		if target := strategy.ChoosePlayer(self, candidates); target != nil {
+			return target.PlayerId.Hex()
+		}
+	}
+	return choice.Options[0]
+}
+
+func cardColor(card types.Card) string {
+	switch deckCard := card.(type) {
+	case *decks.ClassicCard:
+		return deckCard.Color
+	case *decks.HexV1Card:
+		return deckCard.Color
+	case *decks.CustomCard:
+		return deckCard.Color
+	}
+	return ""
+}
+
+func isWild(card types.Card) bool {
+	color := cardColor(card)
+	return color == "black" || color == "rainbow" || color == decks.CustomWildColor
+}
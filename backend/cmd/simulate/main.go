@@ -0,0 +1,284 @@
+// Command simulate plays games between scripted bot players fully in memory to
+// compare the balance of the card decks. No database or sockets are involved.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/HexCardGames/HexDeck/bots"
+	"github.com/HexCardGames/HexDeck/decks"
+	"github.com/HexCardGames/HexDeck/types"
+	"github.com/HexCardGames/HexDeck/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type simulationStats struct {
+	Games           int
+	UnfinishedGames int
+	TotalTurns      int
+	WinsBySeat      []int
+	ActionsPlayed   map[string]int
+	// Number of samples per hand size, taken for every player after each turn
+	HandSizes    map[int]int
+	LeaderChecks map[string]int
+	LeaderFlips  map[string]int
+}
+
+func newSimulationStats(players int) *simulationStats {
+	return &simulationStats{
+		WinsBySeat:    make([]int, players),
+		ActionsPlayed: make(map[string]int),
+		HandSizes:     make(map[int]int),
+		LeaderChecks:  make(map[string]int),
+		LeaderFlips:   make(map[string]int),
+	}
+}
+
+func createRoom(cardDeckId int, players int, options types.GameOptions, seed int64) *types.Room {
+	room := &types.Room{
+		RoomId:       bson.NewObjectID(),
+		GameState:    types.StateRunning,
+		GameOptions:  options,
+		CardDeckId:   cardDeckId,
+		Players:      make([]*types.Player, players),
+		Spectators:   make([]*types.Player, 0),
+		PlayersMutex: &sync.Mutex{},
+		ActionMutex:  &sync.Mutex{},
+		Seed:         seed,
+		Random:       utils.NewRandom(seed),
+	}
+	for i := range players {
+		room.Players[i] = &types.Player{
+			PlayerId: bson.NewObjectID(),
+			Username: fmt.Sprintf("Seat %d", i),
+			IsBot:    true,
+			Cards:    make([]types.Card, 0),
+			Mutex:    &sync.Mutex{},
+		}
+	}
+	room.CardDeck = decks.NewDeck(cardDeckId)
+	room.CardDeck.Init(room)
+	return room
+}
+
+func cardSymbol(card types.Card) string {
+	switch deckCard := card.(type) {
+	case *decks.ClassicCard:
+		return deckCard.Symbol
+	case *decks.HexV1Card:
+		return deckCard.Symbol
+	case *decks.CustomCard:
+		return deckCard.Symbol
+	}
+	return ""
+}
+
+func findActivePlayer(room *types.Room) (int, *types.Player) {
+	for i, player := range room.Players {
+		if room.CardDeck.IsPlayerActive(player) {
+			return i, player
+		}
+	}
+	return -1, nil
+}
+
+// findLeader returns the seat of the player holding the fewest cards, or -1 on a tie
+func findLeader(room *types.Room) int {
+	leader := -1
+	tied := false
+	for i, player := range room.Players {
+		if leader == -1 || len(player.Cards) < len(room.Players[leader].Cards) {
+			leader = i
+			tied = false
+		} else if len(player.Cards) == len(room.Players[leader].Cards) {
+			tied = true
+		}
+	}
+	if tied {
+		return -1
+	}
+	return leader
+}
+
+// resolveChoices answers all pending choices like a bot would
+func resolveChoices(room *types.Room, strategy bots.Strategy) {
+	deck := room.CardDeck
+	for choice := deck.GetPendingChoice(); choice != nil; choice = deck.GetPendingChoice() {
+		var choosingPlayer *types.Player
+		for _, player := range room.Players {
+			if player.PlayerId == choice.PlayerId {
+				choosingPlayer = player
+			}
+		}
+		if choosingPlayer == nil {
+			deck.TimeoutTurn()
+			continue
+		}
+		answer := bots.ChooseAnswer(strategy, choosingPlayer, room.Players, choice)
+		if deck.ResolveChoice(choosingPlayer, answer) == nil {
+			deck.TimeoutTurn()
+		}
+	}
+}
+
+// simulateGame plays a single game and returns the seat of the winner, or -1 if
+// no player won within maxTurns
+func simulateGame(room *types.Room, strategy bots.Strategy, maxTurns int, stats *simulationStats) int {
+	deck := room.CardDeck
+	for turn := 0; turn < maxTurns; turn++ {
+		seat, player := findActivePlayer(room)
+		if player == nil {
+			return -1
+		}
+		stats.TotalTurns += 1
+		for _, roomPlayer := range room.Players {
+			stats.HandSizes[len(roomPlayer.Cards)] += 1
+		}
+
+		cardIndex := strategy.ChooseCard(player.Cards, deck.GetTopCard(), deck.CanPlay)
+		if cardIndex >= 0 && deck.CanPlay(player.Cards[cardIndex]) {
+			card := player.Cards[cardIndex]
+			player.Cards = append(player.Cards[:cardIndex], player.Cards[cardIndex+1:]...)
+			symbol := cardSymbol(card)
+			leaderBefore := findLeader(room)
+			deck.PlayCard(card)
+			resolveChoices(room, strategy)
+
+			if strings.HasPrefix(symbol, "action:") {
+				stats.ActionsPlayed[symbol] += 1
+			}
+			if symbol == "action:swap" || symbol == "action:shuffle" {
+				stats.LeaderChecks[symbol] += 1
+				if findLeader(room) != leaderBefore {
+					stats.LeaderFlips[symbol] += 1
+				}
+			}
+			if len(player.Cards) == 0 {
+				return seat
+			}
+			continue
+		}
+
+		if _, err := deck.DrawCard(); err == types.ErrDrawNotAllowed {
+			deck.TimeoutTurn()
+		}
+	}
+	return -1
+}
+
+func printStats(name string, players int, stats *simulationStats) {
+	finishedGames := stats.Games - stats.UnfinishedGames
+	fmt.Printf("=== %s (%d games, %d players) ===\n", name, stats.Games, players)
+	if stats.Games > 0 {
+		fmt.Printf("Average game length: %.1f turns\n", float64(stats.TotalTurns)/float64(stats.Games))
+	}
+	fmt.Printf("Unfinished games: %d\n", stats.UnfinishedGames)
+
+	fmt.Println("Win rate by seat:")
+	for seat, wins := range stats.WinsBySeat {
+		fmt.Printf("  Seat %d: %5.1f%%\n", seat, percentage(wins, finishedGames))
+	}
+
+	fmt.Println("Action cards played per game:")
+	symbols := make([]string, 0, len(stats.ActionsPlayed))
+	for symbol := range stats.ActionsPlayed {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		fmt.Printf("  %-16s %6.2f\n", symbol, float64(stats.ActionsPlayed[symbol])/float64(stats.Games))
+	}
+
+	fmt.Println("Hand size distribution:")
+	samples := 0
+	maxSize := 0
+	for size, count := range stats.HandSizes {
+		samples += count
+		maxSize = max(maxSize, size)
+	}
+	for size := 0; size <= maxSize; size++ {
+		if stats.HandSizes[size] == 0 {
+			continue
+		}
+		share := percentage(stats.HandSizes[size], samples)
+		fmt.Printf("  %3d cards: %5.1f%% %s\n", size, share, strings.Repeat("#", int(share)))
+	}
+
+	if len(stats.LeaderChecks) > 0 {
+		fmt.Println("Leader flips:")
+		for _, symbol := range []string{"action:swap", "action:shuffle"} {
+			checks := stats.LeaderChecks[symbol]
+			if checks == 0 {
+				continue
+			}
+			fmt.Printf("  %-16s %5.1f%% of %d plays\n", symbol, percentage(stats.LeaderFlips[symbol], checks), checks)
+		}
+	}
+	fmt.Println()
+}
+
+func percentage(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
+
+func main() {
+	games := flag.Int("games", 1000, "number of games to simulate per deck")
+	players := flag.Int("players", 4, "number of players per game")
+	cardDeckId := flag.Int("deck", -1, "ID of the card deck to simulate, -1 simulates all decks")
+	seed := flag.Int64("seed", 0, "seed of the first game, 0 picks a random seed")
+	maxTurns := flag.Int("max-turns", 2000, "number of turns after which a game counts as unfinished")
+	optionsJson := flag.String("options", "", "JSON encoded game options applied on top of the defaults")
+	decksDir := flag.String("decks-dir", "", "directory of custom card deck definitions to load")
+	flag.Parse()
+	if *decksDir != "" {
+		decks.LoadCustomDecks(*decksDir)
+	}
+
+	if *players < 2 {
+		log.Fatal("At least two players are required")
+	}
+	options := types.DefaultGameOptions()
+	if *optionsJson != "" {
+		if err := json.Unmarshal([]byte(*optionsJson), &options); err != nil {
+			log.Fatalf("Parsing game options failed: %v", err)
+		}
+	}
+	if *seed == 0 {
+		*seed = utils.NewSeed()
+	}
+	fmt.Printf("Base seed: %d\n\n", *seed)
+
+	definitions := decks.ListDecks()
+	if *cardDeckId != -1 {
+		definition, ok := decks.GetDeck(*cardDeckId)
+		if !ok {
+			log.Fatalf("No card deck exists with ID %d", *cardDeckId)
+		}
+		definitions = []decks.DeckDefinition{definition}
+	}
+
+	strategy := bots.SimpleStrategy{}
+	for _, definition := range definitions {
+		stats := newSimulationStats(*players)
+		for game := 0; game < *games; game++ {
+			room := createRoom(definition.Id, *players, options, *seed+int64(game))
+			winner := simulateGame(room, strategy, *maxTurns, stats)
+			stats.Games += 1
+			if winner == -1 {
+				stats.UnfinishedGames += 1
+				continue
+			}
+			stats.WinsBySeat[winner] += 1
+		}
+		printStats(definition.Name, *players, stats)
+	}
+}
@@ -57,6 +57,31 @@ func (conn *DatabaseConnection) UpdateRoom(room *types.Room) {
 	}
 }
 
+func (conn *DatabaseConnection) InsertEvent(event types.GameEvent) {
+	_, err := conn.client.Database("hexdeck").Collection("events").InsertOne(context.TODO(), event)
+	if err != nil {
+		slog.Error("Error while inserting event into database", "error", err)
+	}
+}
+
+func (conn *DatabaseConnection) QueryEvents(roomId bson.ObjectID) ([]bson.M, bool) {
+	// Decode nested documents as maps so the events can be passed on as JSON
+	collection := conn.client.Database("hexdeck").Collection("events", options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}))
+	res, err := collection.Find(context.TODO(), bson.D{{Key: "roomid", Value: roomId}}, options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}}).SetProjection(bson.D{{Key: "_id", Value: 0}}))
+	if err != nil {
+		slog.Error("Loading events from database failed", "error", err)
+		return nil, false
+	}
+
+	events := make([]bson.M, 0)
+	err = res.All(context.TODO(), &events)
+	if err != nil {
+		slog.Error("Decoding events from database failed", "error", err)
+		return nil, false
+	}
+	return events, true
+}
+
 func (conn *DatabaseConnection) IncrementGamesPlayed() {
 	conn.client.Database("hexdeck").Collection("global_stats").UpdateOne(context.TODO(), bson.D{}, bson.D{
 		{Key: "$inc", Value: bson.D{{Key: "games_played", Value: 1}}},
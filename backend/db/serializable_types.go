@@ -5,15 +5,20 @@ import (
 
 	"github.com/HexCardGames/HexDeck/decks"
 	"github.com/HexCardGames/HexDeck/types"
+	"github.com/HexCardGames/HexDeck/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type SerializablePlayer struct {
-	PlayerId     bson.ObjectID
-	SessionToken string
-	Username     string
-	Permissions  int
-	Cards        []bson.D
+	PlayerId         bson.ObjectID
+	SessionToken     string
+	Username         string
+	Permissions      int
+	DeclaredLastCard bool
+	Score            int
+	Team             int
+	IsBot            bool
+	Cards            []bson.D
 }
 
 func (serializable *SerializablePlayer) ToPlayer(cardDeckId int) types.Player {
@@ -22,29 +27,43 @@ func (serializable *SerializablePlayer) ToPlayer(cardDeckId int) types.Player {
 		cards[i] = decks.CardFromInterface(cardDeckId, card)
 	}
 	player := types.Player{
-		PlayerId:     serializable.PlayerId,
-		SessionToken: serializable.SessionToken,
-		Username:     serializable.Username,
-		Permissions:  serializable.Permissions,
-		Connection:   types.WebsocketConnection{IsConnected: false},
-		Cards:        cards,
-		Mutex:        &sync.Mutex{},
+		PlayerId:         serializable.PlayerId,
+		SessionToken:     serializable.SessionToken,
+		Username:         serializable.Username,
+		Permissions:      serializable.Permissions,
+		DeclaredLastCard: serializable.DeclaredLastCard,
+		Score:            serializable.Score,
+		Team:             serializable.Team,
+		IsBot:            serializable.IsBot,
+		Connection:       types.WebsocketConnection{IsConnected: false},
+		Cards:            cards,
+		Mutex:            &sync.Mutex{},
 	}
 	player.ResetInactivity()
 	return player
 }
 
 type SerializableRoom struct {
-	RoomId      bson.ObjectID `bson:"_id"`
-	JoinCode    string
-	GameState   types.GameState
-	GameOptions types.GameOptions
-	CardDeckId  int
-	CardDeck    bson.D
-	Players     []SerializablePlayer
-	OwnerId     bson.ObjectID
-	MoveTimeout int
-	Winner      *bson.ObjectID
+	RoomId          bson.ObjectID `bson:"_id"`
+	JoinCode        string
+	GameState       types.GameState
+	GameOptions     bson.Raw
+	CardDeckId      int
+	CardDeck        bson.D
+	Players         []SerializablePlayer
+	Spectators      []SerializablePlayer
+	OwnerId         bson.ObjectID
+	Seed            int64
+	Random          *utils.Random
+	MoveTimeout     int
+	CatchablePlayer *bson.ObjectID
+	Round           int
+	NextRoundIn     int
+	Paused          bool
+	EventSequence   int
+	Winner          *bson.ObjectID
+	WinningTeam     int
+	Ranking         []bson.ObjectID
 }
 
 func (serializable SerializableRoom) ToRoom() *types.Room {
@@ -53,20 +72,48 @@ func (serializable SerializableRoom) ToRoom() *types.Room {
 		player := serializablePlayer.ToPlayer(serializable.CardDeckId)
 		players[i] = &player
 	}
+	spectators := make([]*types.Player, len(serializable.Spectators))
+	for i, serializableSpectator := range serializable.Spectators {
+		spectator := serializableSpectator.ToPlayer(serializable.CardDeckId)
+		spectators[i] = &spectator
+	}
+	// Options missing from rooms stored by older versions keep their defaults
+	gameOptions := types.DefaultGameOptions()
+	if len(serializable.GameOptions) > 0 {
+		bson.Unmarshal(serializable.GameOptions, &gameOptions)
+	}
 	cardDeck := decks.DeckFromInterface(serializable.CardDeckId, serializable.CardDeck)
 	room := &types.Room{
-		RoomId:       serializable.RoomId,
-		JoinCode:     serializable.JoinCode,
-		GameState:    serializable.GameState,
-		GameOptions:  serializable.GameOptions,
-		CardDeckId:   serializable.CardDeckId,
-		CardDeck:     cardDeck,
-		Players:      players,
-		PlayersMutex: &sync.Mutex{},
-		OwnerId:      serializable.OwnerId,
-		MoveTimeout:  serializable.MoveTimeout,
-		Winner:       serializable.Winner,
+		RoomId:          serializable.RoomId,
+		JoinCode:        serializable.JoinCode,
+		GameState:       serializable.GameState,
+		GameOptions:     gameOptions,
+		CardDeckId:      serializable.CardDeckId,
+		CardDeck:        cardDeck,
+		Players:         players,
+		Spectators:      spectators,
+		PlayersMutex:    &sync.Mutex{},
+		ActionMutex:     &sync.Mutex{},
+		OwnerId:         serializable.OwnerId,
+		Seed:            serializable.Seed,
+		Random:          serializable.Random,
+		MoveTimeout:     serializable.MoveTimeout,
+		CatchablePlayer: serializable.CatchablePlayer,
+		Round:           serializable.Round,
+		NextRoundIn:     serializable.NextRoundIn,
+		Paused:          serializable.Paused,
+		EventSequence:   serializable.EventSequence,
+		Winner:          serializable.Winner,
+		WinningTeam:     serializable.WinningTeam,
+		Ranking:         serializable.Ranking,
+	}
+	if room.Random == nil {
+		// Rooms stored before seeded randomness existed start a new sequence
+		room.Random = utils.NewRandom(room.Seed)
+	}
+	room.ResetTurnTimer()
+	if cardDeck != nil {
+		cardDeck.SetRoom(room)
 	}
-	cardDeck.SetRoom(room)
 	return room
 }
@@ -0,0 +1,72 @@
+package decks
+
+import (
+	"github.com/HexCardGames/HexDeck/types"
+	"github.com/HexCardGames/HexDeck/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Helpers for the pending choices shared by all decks
+
+func newColorChoice(player *types.Player, colors []string) *types.PendingChoice {
+	return &types.PendingChoice{
+		Kind:     types.ChoiceColor,
+		PlayerId: player.PlayerId,
+		Options:  append([]string{}, colors...),
+	}
+}
+
+// newPlayerChoice asks the player to target one of the other players. It
+// returns nil if there is nobody else to choose.
+func newPlayerChoice(room *types.Room, player *types.Player) *types.PendingChoice {
+	options := make([]string, 0, len(room.Players))
+	for _, target := range room.Players {
+		if target != player && !room.HasFinished(target) {
+			options = append(options, target.PlayerId.Hex())
+		}
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return &types.PendingChoice{
+		Kind:     types.ChoicePlayer,
+		PlayerId: player.PlayerId,
+		Options:  options,
+	}
+}
+
+// newSwapChoice asks the player for the target of a hand swap. It returns nil
+// if the player just played their last card and has nothing left to swap.
+func newSwapChoice(room *types.Room, player *types.Player) *types.PendingChoice {
+	if len(player.Cards) == 0 {
+		return nil
+	}
+	return newPlayerChoice(room, player)
+}
+
+// isValidAnswer checks that the choice is waiting for this player and the
+// answer is one of its options
+func isValidAnswer(choice *types.PendingChoice, player *types.Player, answer string) bool {
+	return choice != nil && player != nil && choice.PlayerId == player.PlayerId && choice.HasOption(answer)
+}
+
+// randomAnswer picks one of the options, it's used when a player times out
+func randomAnswer(choice *types.PendingChoice, random *utils.Random) string {
+	if len(choice.Options) == 0 {
+		return ""
+	}
+	return choice.Options[random.IntN(len(choice.Options))]
+}
+
+func findPlayerByHex(room *types.Room, playerId string) *types.Player {
+	targetId, err := bson.ObjectIDFromHex(playerId)
+	if err != nil {
+		return nil
+	}
+	for _, player := range room.Players {
+		if player.PlayerId == targetId {
+			return player
+		}
+	}
+	return nil
+}
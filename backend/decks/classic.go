@@ -5,14 +5,41 @@ import (
 
 	"github.com/HexCardGames/HexDeck/types"
 	"github.com/HexCardGames/HexDeck/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+const ClassicDeckId = 0
+
+func init() {
+	RegisterDeck(DeckDefinition{
+		Id:      ClassicDeckId,
+		Name:    "Classic",
+		NewDeck: func() types.CardDeck { return &Classic{} },
+		NewCard: func() types.Card { return &ClassicCard{} },
+	})
+}
+
 type Classic struct {
 	room              *types.Room
 	CardsPlayed       []*ClassicCard
 	CardsRemaining    []*ClassicCard
 	DirectionReversed bool
 	ActivePlayer      int
+	// Cards the active player has to draw unless they stack another draw card
+	PendingDraw int
+	// Playable card the active player just drew; it's the only card they can
+	// play before passing their turn
+	DrawnCard *ClassicCard
+	// Snapshot of the last Wild Draw Four while it can still be challenged
+	Challenge *ClassicChallenge
+	// Decision the game waits for, like the color of a wildcard
+	Choice *types.PendingChoice
+}
+
+type ClassicChallenge struct {
+	PlayerId      bson.ObjectID
+	PreviousColor string
+	Hand          []*ClassicCard
 }
 
 var ClassicColors = []string{"red", "yellow", "blue", "green"}
@@ -39,7 +66,7 @@ func (deck *Classic) fillDeck() {
 		cards[offset+1] = &ClassicCard{Symbol: "action:draw_4", Color: "black"}
 		offset += 2
 	}
-	utils.ShuffleSlice(&cards)
+	utils.ShuffleSlice(&cards, deck.room.Random)
 	deck.CardsRemaining = cards
 }
 
@@ -47,33 +74,47 @@ func (deck *Classic) Init(room *types.Room) {
 	deck.room = room
 	deck.DirectionReversed = false
 	deck.ActivePlayer = 0
+	deck.PendingDraw = 0
+	deck.DrawnCard = nil
+	deck.Challenge = nil
+	deck.Choice = nil
 	deck.fillDeck()
 
+	handSize := startingHandSize(room)
+
 	deck.room.PlayersMutex.Lock()
 	defer deck.room.PlayersMutex.Unlock()
 	for _, player := range deck.room.Players {
 		player.Mutex.Lock()
 		defer player.Mutex.Unlock()
-		deck.drawMany(player, 7)
+		deck.drawMany(player, handSize)
 	}
 }
 
 func (deck *Classic) SetRoom(room *types.Room) {
 	deck.room = room
+	if len(room.Players) > 0 {
+		deck.DrawnCard = relinkCard(deck.DrawnCard, room.Players[deck.getActivePlayer()])
+	}
+	deck.restoreColorChoice()
 }
 
-func (deck *Classic) IsEmpty() bool {
-	if len(deck.CardsRemaining) == 0 {
-		deck.fillDeck()
+// restoreColorChoice asks the active player for a color again if a room was
+// stored with an uncolored wildcard before pending choices existed
+func (deck *Classic) restoreColorChoice() {
+	topCard := deck.getTopCard()
+	if deck.Choice != nil || topCard == nil || topCard.Color != "black" || len(deck.room.Players) == 0 {
+		return
 	}
-	return false
+	deck.Choice = newColorChoice(deck.room.Players[deck.getActivePlayer()], ClassicColors)
+}
+
+func (deck *Classic) IsEmpty() bool {
+	return isPileEmpty(&deck.CardsPlayed, &deck.CardsRemaining, deck.room.Random, (*ClassicCard).reset)
 }
 
 func (deck *Classic) getTopCard() *ClassicCard {
-	if len(deck.CardsPlayed) == 0 {
-		return nil
-	}
-	return deck.CardsPlayed[len(deck.CardsPlayed)-1]
+	return getTopCard(deck.CardsPlayed)
 }
 
 func (deck *Classic) GetTopCard() types.Card {
@@ -84,11 +125,7 @@ func (deck *Classic) drawCard(player *types.Player) types.Card {
 	if deck.IsEmpty() {
 		return nil
 	}
-
-	card := deck.CardsRemaining[0]
-	deck.CardsRemaining = deck.CardsRemaining[1:]
-	player.Cards = append(player.Cards, card)
-	return card
+	return takeCard(&deck.CardsRemaining, player)
 }
 
 func (deck *Classic) drawMany(player *types.Player, cards int) {
@@ -101,24 +138,105 @@ func (deck *Classic) getActivePlayer() int {
 	return utils.Mod(deck.ActivePlayer, len(deck.room.Players))
 }
 
-func (deck *Classic) DrawCard() types.Card {
+func (deck *Classic) DrawCard() (types.Card, error) {
 	// Can't draw another card before wildcard color is selected
 	topCard := deck.getTopCard()
 	if topCard != nil && topCard.Color == "black" {
-		return nil
+		return nil, types.ErrDrawNotAllowed
+	}
+	if deck.DrawnCard != nil || deck.Choice != nil {
+		return nil, types.ErrDrawNotAllowed
+	}
+
+	player := deck.room.Players[deck.getActivePlayer()]
+	if deck.PendingDraw > 0 {
+		card := deck.drawPendingPenalty(player)
+		deck.nextPlayer()
+		if card == nil {
+			return nil, types.ErrDeckExhausted
+		}
+		return card, nil
+	}
+
+	options := deck.room.GameOptions
+	if options.MustPlayIfAble && deck.hasPlayableCard(player) {
+		return nil, types.ErrDrawNotAllowed
+	}
+
+	card := deck.drawCard(player)
+	if card == nil {
+		deck.nextPlayer()
+		return nil, types.ErrDeckExhausted
+	}
+	for options.DrawUntilPlayable && !deck.CanPlay(card) {
+		nextCard := deck.drawCard(player)
+		if nextCard == nil {
+			break
+		}
+		card = nextCard
 	}
+	if deck.CanPlay(card) {
+		deck.DrawnCard = card.(*ClassicCard)
+		return card, nil
+	}
+	deck.nextPlayer()
+	return card, nil
+}
 
-	card := deck.drawCard(deck.room.Players[deck.getActivePlayer()])
+func (deck *Classic) PassTurn() bool {
+	if deck.DrawnCard == nil {
+		return false
+	}
+	deck.DrawnCard = nil
 	deck.nextPlayer()
+	return true
+}
+
+func (deck *Classic) SkipTurn() bool {
+	topCard := deck.getTopCard()
+	if deck.Choice != nil || deck.PendingDraw > 0 || (topCard != nil && topCard.Color == "black") {
+		return false
+	}
+	deck.DrawnCard = nil
+	deck.nextPlayer()
+	return true
+}
+
+func (deck *Classic) drawPendingPenalty(player *types.Player) types.Card {
+	var card types.Card
+	for range deck.PendingDraw {
+		if drawnCard := deck.drawCard(player); drawnCard != nil {
+			card = drawnCard
+		}
+	}
+	deck.PendingDraw = 0
+	deck.Challenge = nil
 	return card
 }
 
+func (deck *Classic) hasPlayableCard(player *types.Player) bool {
+	for _, card := range player.Cards {
+		if deck.CanPlay(card) {
+			return true
+		}
+	}
+	return false
+}
+
+func (deck *Classic) DrawCards(player *types.Player, amount int) {
+	deck.drawMany(player, amount)
+}
+
+// getNextPlayer returns the next player in the direction of play, skipping
+// players who already finished
 func (deck *Classic) getNextPlayer() int {
 	direction := 1
 	if deck.DirectionReversed {
 		direction = -1
 	}
-	return utils.Mod((deck.ActivePlayer + direction), len(deck.room.Players))
+	return findNextSeat(deck.room, len(deck.room.Players), deck.ActivePlayer+direction, direction, func(seat int) *types.Player {
+		return deck.room.Players[seat]
+	})
 }
 
 func (deck *Classic) nextPlayer() {
@@ -131,7 +249,20 @@ func (deck *Classic) CanPlay(card types.Card) bool {
 	if topCard == nil || checkCard == nil {
 		return topCard == nil
 	}
-	return topCard.Color != "black" && (checkCard.Color == "black" || checkCard.Color == topCard.Color || checkCard.Symbol == topCard.Symbol)
+	if topCard.Color == "black" || deck.Choice != nil {
+		return false
+	}
+	if deck.DrawnCard != nil && checkCard != deck.DrawnCard {
+		return false
+	}
+	if deck.PendingDraw > 0 {
+		// A pending draw penalty can only be answered with the same draw card
+		return deck.room.GameOptions.DrawStacking && checkCard.Symbol == topCard.Symbol
+	}
+	if checkCard.Color == "black" {
+		return !topCard.isWild() || deck.room.GameOptions.AllowWildOnWild
+	}
+	return checkCard.Color == topCard.Color || checkCard.Symbol == topCard.Symbol
 }
 
 func (deck *Classic) PlayCard(card types.Card) bool {
@@ -139,54 +270,267 @@ func (deck *Classic) PlayCard(card types.Card) bool {
 		return false
 	}
 	deckCard := card.(*ClassicCard)
+	if deckCard.Symbol == "action:draw_4" {
+		deck.snapshotChallenge()
+	}
 	deck.CardsPlayed = append(deck.CardsPlayed, deckCard)
+	deck.DrawnCard = nil
 
 	if deckCard.Symbol == "action:skip" {
 		deck.nextPlayer()
 	} else if deckCard.Symbol == "action:draw_2" || deckCard.Symbol == "action:draw_4" {
-		targetPlayer := deck.room.Players[deck.getNextPlayer()]
 		amount := 2
 		if deckCard.Symbol == "action:draw_4" {
 			amount = 4
 		}
-		for range amount {
-			deck.drawCard(targetPlayer)
+		options := deck.room.GameOptions
+		if options.DrawStacking || (options.DrawFourChallenge && deckCard.Symbol == "action:draw_4") {
+			deck.PendingDraw += amount
+		} else {
+			deck.drawMany(deck.room.Players[deck.getNextPlayer()], amount)
 		}
 	} else if deckCard.Symbol == "action:reverse" {
 		deck.DirectionReversed = !deck.DirectionReversed
+	} else if deck.room.GameOptions.SevenZero && len(deck.room.Players[deck.getActivePlayer()].Cards) > 0 {
+		// Seven-zero rule; a player who just played their last card has already won
+		if deckCard.Symbol == "7" {
+			deck.Choice = newPlayerChoice(deck.room, deck.room.Players[deck.getActivePlayer()])
+		}
+		if deckCard.Symbol == "0" {
+			deck.rotateHands()
+		}
 	}
 
-	if deckCard.Color != "black" {
+	if deckCard.Color == "black" {
+		deck.Choice = newColorChoice(deck.room.Players[deck.getActivePlayer()], ClassicColors)
+	}
+	if deck.Choice == nil {
 		deck.nextPlayer()
 	}
 
 	return true
 }
 
-func (deck *Classic) UpdatePlayedCard(cardData interface{}) types.Card {
+// CanPlayCards allows playing several cards with the same number at once
+func (deck *Classic) CanPlayCards(cards []types.Card) bool {
+	if len(cards) == 0 || !deck.CanPlay(cards[0]) {
+		return false
+	}
+	if len(cards) == 1 {
+		return true
+	}
+	firstCard := cards[0].(*ClassicCard)
+	if _, err := strconv.Atoi(firstCard.Symbol); err != nil || deck.DrawnCard != nil {
+		return false
+	}
+	if deck.room.GameOptions.SevenZero && firstCard.Symbol == "7" {
+		// Only a single hand swap per move
+		return false
+	}
+	for _, card := range cards[1:] {
+		if card.(*ClassicCard).Symbol != firstCard.Symbol {
+			return false
+		}
+	}
+	return true
+}
+
+func (deck *Classic) PlayCards(cards []types.Card) bool {
+	if !deck.CanPlayCards(cards) {
+		return false
+	}
+	activePlayer := deck.ActivePlayer
+	for _, card := range cards {
+		// All cards are played by the same player, only the last one ends the turn
+		deck.ActivePlayer = activePlayer
+		if !deck.PlayCard(card) {
+			return false
+		}
+	}
+	return true
+}
+
+// rotateHands passes every hand one seat on in the direction of play
+func (deck *Classic) rotateHands() {
+	players := make([]*types.Player, 0, len(deck.room.Players))
+	for _, player := range deck.room.Players {
+		if !deck.room.HasFinished(player) {
+			players = append(players, player)
+		}
+	}
+	direction := 1
+	if deck.DirectionReversed {
+		direction = -1
+	}
+	hands := make([][]types.Card, len(players))
+	for i, player := range players {
+		hands[utils.Mod(i+direction, len(players))] = player.Cards
+	}
+	for i, player := range players {
+		player.Cards = hands[i]
+	}
+}
+
+// swapHands swaps the active player's hand with the chosen player's hand
+func (deck *Classic) swapHands(playerId string) {
+	player := deck.room.Players[deck.getActivePlayer()]
+	target := findPlayerByHex(deck.room, playerId)
+	if target == nil || target == player {
+		return
+	}
+	player.Cards, target.Cards = target.Cards, player.Cards
+}
+
+// snapshotChallenge stores the active player's hand and the current color
+// before a Wild Draw Four is played, so a challenge can be judged later
+func (deck *Classic) snapshotChallenge() {
+	deck.Challenge = nil
 	topCard := deck.getTopCard()
-	if topCard.Color != "black" {
-		return nil
+	if !deck.room.GameOptions.DrawFourChallenge || topCard == nil {
+		return
+	}
+	player := deck.room.Players[deck.getActivePlayer()]
+	hand := make([]*ClassicCard, len(player.Cards))
+	for i, card := range player.Cards {
+		hand[i] = card.(*ClassicCard)
 	}
-	updateData, ok := cardData.(map[string]interface{})
-	if !ok {
+	deck.Challenge = &ClassicChallenge{
+		PlayerId:      player.PlayerId,
+		PreviousColor: topCard.Color,
+		Hand:          hand,
+	}
+}
+
+func (deck *Classic) ChallengeDrawFour() *types.ChallengeResult {
+	topCard := deck.getTopCard()
+	if deck.Challenge == nil || deck.PendingDraw == 0 || topCard == nil || topCard.Color == "black" {
 		return nil
 	}
-	newColor, ok := updateData["Color"].(string)
-	if !ok {
+	var challenged *types.Player
+	for _, player := range deck.room.Players {
+		if player.PlayerId == deck.Challenge.PlayerId {
+			challenged = player
+		}
+	}
+	challenger := deck.room.Players[deck.getActivePlayer()]
+	if challenged == nil || challenged == challenger {
 		return nil
 	}
 
-	for _, color := range ClassicColors {
-		if newColor == color {
-			deck.nextPlayer()
-			topCard.Color = color
-			return topCard
+	result := &types.ChallengeResult{
+		Challenger:    challenger,
+		Challenged:    challenged,
+		Successful:    false,
+		PreviousColor: deck.Challenge.PreviousColor,
+		Hand:          make([]types.Card, len(deck.Challenge.Hand)),
+	}
+	for i, card := range deck.Challenge.Hand {
+		result.Hand[i] = card
+		if card.Color == deck.Challenge.PreviousColor {
+			result.Successful = true
+		}
+	}
+
+	if result.Successful {
+		// The challenged player draws the penalty and the challenger continues their turn
+		result.Penalty = deck.PendingDraw
+		deck.drawMany(challenged, result.Penalty)
+	} else {
+		result.Penalty = deck.PendingDraw + 2
+		deck.drawMany(challenger, result.Penalty)
+		deck.nextPlayer()
+	}
+	deck.PendingDraw = 0
+	deck.Challenge = nil
+	return result
+}
+
+func (deck *Classic) CanJumpIn(player *types.Player, card types.Card) bool {
+	topCard := deck.getTopCard()
+	checkCard := card.(*ClassicCard)
+	if !deck.room.GameOptions.JumpIn || topCard == nil || topCard.Color == "black" || deck.PendingDraw > 0 || deck.Choice != nil {
+		return false
+	}
+	return *checkCard == *topCard && deck.getPlayerIndex(player) != -1 && !deck.IsPlayerActive(player)
+}
+
+func (deck *Classic) JumpIn(player *types.Player, card types.Card) bool {
+	if !deck.CanJumpIn(player, card) {
+		return false
+	}
+	deck.ActivePlayer = deck.getPlayerIndex(player)
+	deck.DrawnCard = nil
+	return deck.PlayCard(card)
+}
+
+func (deck *Classic) getPlayerIndex(target *types.Player) int {
+	for i, player := range deck.room.Players {
+		if player == target {
+			return i
 		}
 	}
+	return -1
+}
+
+func (deck *Classic) GetPendingChoice() *types.PendingChoice {
+	return deck.Choice
+}
+
+func (deck *Classic) ResolveChoice(player *types.Player, answer string) types.Card {
+	if !isValidAnswer(deck.Choice, player, answer) {
+		return nil
+	}
+	return deck.resolveChoice(answer)
+}
+
+func (deck *Classic) resolveChoice(answer string) types.Card {
+	topCard := deck.getTopCard()
+	if topCard == nil {
+		return nil
+	}
+	switch deck.Choice.Kind {
+	case types.ChoiceColor:
+		topCard.Color = answer
+	case types.ChoicePlayer:
+		deck.swapHands(answer)
+	}
+	deck.Choice = nil
+	deck.nextPlayer()
+	return topCard
+}
+
+func (deck *Classic) TimeoutTurn() types.Card {
+	if deck.Choice != nil {
+		return deck.resolveChoice(randomAnswer(deck.Choice, deck.room.Random))
+	}
+	if deck.PassTurn() {
+		return nil
+	}
+	player := deck.room.Players[deck.getActivePlayer()]
+	if deck.PendingDraw > 0 {
+		deck.drawPendingPenalty(player)
+	} else {
+		deck.drawCard(player)
+	}
+	deck.nextPlayer()
 	return nil
 }
 
+func (deck *Classic) GetCardPoints(card types.Card) int {
+	deckCard := card.(*ClassicCard)
+	if value, err := strconv.Atoi(deckCard.Symbol); err == nil {
+		return value
+	}
+	if deckCard.isWild() {
+		return 50
+	}
+	return 20
+}
+
+func (deck *Classic) GetPendingDraw() int {
+	return deck.PendingDraw
+}
+
 func (deck *Classic) IsPlayerActive(target *types.Player) bool {
 	return deck.room.Players[utils.Mod(deck.ActivePlayer, len(deck.room.Players))] == target
 }
@@ -195,3 +539,14 @@ type ClassicCard struct {
 	Symbol string
 	Color  string
 }
+
+func (card *ClassicCard) isWild() bool {
+	return card.Symbol == "action:wildcard" || card.Symbol == "action:draw_4"
+}
+
+// reset clears the color chosen for a wildcard
+func (card *ClassicCard) reset() {
+	if card.isWild() {
+		card.Color = "black"
+	}
+}
@@ -0,0 +1,458 @@
+package decks
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HexCardGames/HexDeck/types"
+	"github.com/HexCardGames/HexDeck/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Custom decks are defined in JSON or YAML files and loaded at startup, e.g.
+//
+//	Id: 100
+//	Name: Tiny
+//	Colors: [red, blue]
+//	Cards:
+//	  - { Symbol: "1", Count: 3, Points: 1 }
+//	  - { Symbol: "action:skip", Count: 2, Points: 20, Effects: [{ Type: skip }] }
+//	  - { Symbol: "action:wild_draw", Count: 2, Points: 50, Wild: true, Effects: [{ Type: draw, Amount: 3 }] }
+//
+// Count is the number of copies per color, or the total number for wild cards.
+// The ID is stored with every room, so it must never change once games exist.
+
+const CustomWildColor = "wild"
+
+type CustomEffectType string
+
+const (
+	EffectSkip    CustomEffectType = "skip"
+	EffectReverse CustomEffectType = "reverse"
+	EffectDraw    CustomEffectType = "draw"
+	EffectSwap    CustomEffectType = "swap"
+	EffectShuffle CustomEffectType = "shuffle"
+)
+
+type CustomEffect struct {
+	Type CustomEffectType `yaml:"Type"`
+	// Number of cards the next player draws for the draw effect
+	Amount int `yaml:"Amount"`
+}
+
+type CustomCardDefinition struct {
+	Symbol  string         `yaml:"Symbol"`
+	Count   int            `yaml:"Count"`
+	Points  int            `yaml:"Points"`
+	Wild    bool           `yaml:"Wild"`
+	Effects []CustomEffect `yaml:"Effects"`
+}
+
+type CustomDeckDefinition struct {
+	Id     int                    `yaml:"Id"`
+	Name   string                 `yaml:"Name"`
+	Colors []string               `yaml:"Colors"`
+	Cards  []CustomCardDefinition `yaml:"Cards"`
+}
+
+func (definition *CustomDeckDefinition) validate() error {
+	if definition.Name == "" {
+		return fmt.Errorf("deck has no name")
+	}
+	if len(definition.Colors) == 0 || len(definition.Cards) == 0 {
+		return fmt.Errorf("deck needs colors and cards")
+	}
+	symbols := make(map[string]bool)
+	for _, card := range definition.Cards {
+		if card.Symbol == "" || symbols[card.Symbol] {
+			return fmt.Errorf("card symbol %q is empty or defined twice", card.Symbol)
+		}
+		symbols[card.Symbol] = true
+		if card.Count < 1 {
+			return fmt.Errorf("card %q needs a positive count", card.Symbol)
+		}
+		for _, effect := range card.Effects {
+			switch effect.Type {
+			case EffectSkip, EffectReverse, EffectSwap, EffectShuffle:
+			case EffectDraw:
+				if effect.Amount < 1 {
+					return fmt.Errorf("draw effect of card %q needs a positive amount", card.Symbol)
+				}
+			default:
+				return fmt.Errorf("card %q has unknown effect %q", card.Symbol, effect.Type)
+			}
+		}
+	}
+	for _, color := range definition.Colors {
+		if color == CustomWildColor {
+			return fmt.Errorf("color %q is reserved for wild cards", CustomWildColor)
+		}
+	}
+	return nil
+}
+
+func (definition *CustomDeckDefinition) getCard(symbol string) *CustomCardDefinition {
+	for i := range definition.Cards {
+		if definition.Cards[i].Symbol == symbol {
+			return &definition.Cards[i]
+		}
+	}
+	return nil
+}
+
+func parseDeckFile(path string) (*CustomDeckDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	definition := &CustomDeckDefinition{}
+	if strings.HasSuffix(path, ".json") {
+		err = json.Unmarshal(data, definition)
+	} else {
+		err = yaml.Unmarshal(data, definition)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return definition, definition.validate()
+}
+
+// LoadCustomDecks registers all deck definitions found in the directory. Invalid
+// files are logged and skipped, a missing directory just means there are none.
+func LoadCustomDecks(directory string) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		slog.Info("No custom card decks loaded", "directory", directory, "error", err)
+		return
+	}
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if entry.IsDir() || (extension != ".json" && extension != ".yaml" && extension != ".yml") {
+			continue
+		}
+		path := filepath.Join(directory, entry.Name())
+		definition, err := parseDeckFile(path)
+		if err != nil {
+			slog.Error("Invalid custom card deck", "path", path, "error", err)
+			continue
+		}
+		registered := RegisterDeck(DeckDefinition{
+			Id:      definition.Id,
+			Name:    definition.Name,
+			NewDeck: func() types.CardDeck { return &Custom{definition: definition} },
+			NewCard: func() types.Card { return &CustomCard{} },
+		})
+		if !registered {
+			slog.Error("Custom card deck ID is already taken", "path", path, "cardDeckId", definition.Id)
+			continue
+		}
+		slog.Info("Loaded custom card deck", "name", definition.Name, "cardDeckId", definition.Id)
+	}
+}
+
+// Custom is the generic deck driven by a CustomDeckDefinition
+type Custom struct {
+	room        *types.Room
+	definition  *CustomDeckDefinition
+	CardsPlayed []*CustomCard
+	// Shuffled draw pile
+	CardsRemaining    []*CustomCard
+	PlayerOrder       []int
+	ActiveIndex       int
+	DirectionReversed bool
+	// Skips of the played card, applied once its pending choices are resolved
+	PendingSkips int
+	// See Classic.DrawnCard
+	DrawnCard *CustomCard
+	// Decision the game waits for, like the color of a wild card
+	Choice *types.PendingChoice
+}
+
+type CustomCard struct {
+	Symbol string
+	Color  string
+}
+
+func (deck *Custom) fillDeck() {
+	cards := make([]*CustomCard, 0)
+	for _, card := range deck.definition.Cards {
+		if card.Wild {
+			for range card.Count {
+				cards = append(cards, &CustomCard{Symbol: card.Symbol, Color: CustomWildColor})
+			}
+			continue
+		}
+		for _, color := range deck.definition.Colors {
+			for range card.Count {
+				cards = append(cards, &CustomCard{Symbol: card.Symbol, Color: color})
+			}
+		}
+	}
+	utils.ShuffleSlice(&cards, deck.room.Random)
+	deck.CardsRemaining = cards
+}
+
+func (deck *Custom) Init(room *types.Room) {
+	deck.room = room
+	deck.PlayerOrder = make([]int, len(room.Players))
+	deck.ActiveIndex = 0
+	deck.DirectionReversed = false
+	deck.PendingSkips = 0
+	deck.DrawnCard = nil
+	deck.Choice = nil
+	deck.fillDeck()
+
+	handSize := startingHandSize(room)
+
+	deck.room.PlayersMutex.Lock()
+	defer deck.room.PlayersMutex.Unlock()
+	for i, player := range deck.room.Players {
+		deck.PlayerOrder[i] = i
+		player.Mutex.Lock()
+		defer player.Mutex.Unlock()
+		deck.drawMany(player, handSize)
+	}
+}
+
+func (deck *Custom) SetRoom(room *types.Room) {
+	deck.room = room
+	deck.DrawnCard = relinkCard(deck.DrawnCard, deck.getPlayer(deck.ActiveIndex))
+}
+
+func (deck *Custom) isWild(card *CustomCard) bool {
+	cardDefinition := deck.definition.getCard(card.Symbol)
+	return cardDefinition != nil && cardDefinition.Wild
+}
+
+func (deck *Custom) IsEmpty() bool {
+	return isPileEmpty(&deck.CardsPlayed, &deck.CardsRemaining, deck.room.Random, deck.resetCard)
+}
+
+// resetCard clears the color chosen for a wild card
+func (deck *Custom) resetCard(card *CustomCard) {
+	if deck.isWild(card) {
+		card.Color = CustomWildColor
+	}
+}
+
+func (deck *Custom) getTopCard() *CustomCard {
+	return getTopCard(deck.CardsPlayed)
+}
+
+func (deck *Custom) GetTopCard() types.Card {
+	return deck.getTopCard()
+}
+
+func (deck *Custom) drawCard(player *types.Player) types.Card {
+	if deck.IsEmpty() {
+		return nil
+	}
+	return takeCard(&deck.CardsRemaining, player)
+}
+
+func (deck *Custom) drawMany(player *types.Player, cards int) {
+	for i := 0; i < cards; i++ {
+		deck.drawCard(player)
+	}
+}
+
+func (deck *Custom) DrawCard() (types.Card, error) {
+	// Can't draw another card before pending choices are resolved
+	if deck.DrawnCard != nil || deck.Choice != nil {
+		return nil, types.ErrDrawNotAllowed
+	}
+
+	card := deck.drawCard(deck.getPlayer(deck.ActiveIndex))
+	if card == nil {
+		deck.nextPlayer()
+		return nil, types.ErrDeckExhausted
+	}
+	if deck.CanPlay(card) {
+		deck.DrawnCard = card.(*CustomCard)
+		return card, nil
+	}
+	deck.nextPlayer()
+	return card, nil
+}
+
+func (deck *Custom) PassTurn() bool {
+	if deck.DrawnCard == nil {
+		return false
+	}
+	deck.DrawnCard = nil
+	deck.nextPlayer()
+	return true
+}
+
+func (deck *Custom) SkipTurn() bool {
+	if deck.Choice != nil {
+		return false
+	}
+	deck.DrawnCard = nil
+	deck.nextPlayer()
+	return true
+}
+
+func (deck *Custom) DrawCards(player *types.Player, amount int) {
+	deck.drawMany(player, amount)
+}
+
+func (deck *Custom) getDirection() int {
+	if deck.DirectionReversed {
+		return -1
+	}
+	return 1
+}
+
+func (deck *Custom) getSeatedPlayer(seat int) *types.Player {
+	return getSeatedPlayer(deck.room, deck.PlayerOrder, seat)
+}
+
+func (deck *Custom) getValidIndex(index int) int {
+	return findNextSeat(deck.room, len(deck.PlayerOrder), index, deck.getDirection(), deck.getSeatedPlayer)
+}
+
+func (deck *Custom) getPlayer(index int) *types.Player {
+	return deck.getSeatedPlayer(deck.getValidIndex(index))
+}
+
+func (deck *Custom) getNextPlayerIndex() int {
+	return deck.getValidIndex(deck.ActiveIndex + deck.getDirection())
+}
+
+func (deck *Custom) nextPlayer() {
+	deck.ActiveIndex = deck.getNextPlayerIndex()
+}
+
+// finishTurn passes the turn on once the played card needs no further choices
+func (deck *Custom) finishTurn() {
+	for i := 0; i <= deck.PendingSkips; i++ {
+		deck.nextPlayer()
+	}
+	deck.PendingSkips = 0
+}
+
+func (deck *Custom) CanPlay(card types.Card) bool {
+	topCard := deck.getTopCard()
+	checkCard := card.(*CustomCard)
+	if topCard == nil || checkCard == nil {
+		return topCard == nil
+	}
+	if deck.Choice != nil || topCard.Color == CustomWildColor {
+		return false
+	}
+	if deck.DrawnCard != nil && checkCard != deck.DrawnCard {
+		return false
+	}
+	return deck.isWild(checkCard) || checkCard.Color == topCard.Color || checkCard.Symbol == topCard.Symbol
+}
+
+func (deck *Custom) PlayCard(card types.Card) bool {
+	if !deck.CanPlay(card) {
+		return false
+	}
+	deckCard := card.(*CustomCard)
+	deck.CardsPlayed = append(deck.CardsPlayed, deckCard)
+	deck.DrawnCard = nil
+	player := deck.getPlayer(deck.ActiveIndex)
+
+	cardDefinition := deck.definition.getCard(deckCard.Symbol)
+	if cardDefinition == nil {
+		deck.finishTurn()
+		return true
+	}
+	for _, effect := range cardDefinition.Effects {
+		switch effect.Type {
+		case EffectSkip:
+			deck.PendingSkips += 1
+		case EffectReverse:
+			deck.DirectionReversed = !deck.DirectionReversed
+		case EffectDraw:
+			deck.drawMany(deck.getPlayer(deck.getNextPlayerIndex()), effect.Amount)
+		case EffectShuffle:
+			utils.ShuffleSlice(&deck.PlayerOrder, deck.room.Random)
+			if seat := getSeat(deck.room, deck.PlayerOrder, player); seat != -1 {
+				deck.ActiveIndex = seat
+			}
+		case EffectSwap:
+			if deck.Choice == nil {
+				deck.Choice = newSwapChoice(deck.room, player)
+			}
+		}
+	}
+
+	if cardDefinition.Wild && deck.Choice == nil {
+		deck.Choice = newColorChoice(player, deck.definition.Colors)
+	}
+	if deck.Choice == nil {
+		deck.finishTurn()
+	}
+	return true
+}
+
+func (deck *Custom) GetPendingChoice() *types.PendingChoice {
+	return deck.Choice
+}
+
+func (deck *Custom) ResolveChoice(player *types.Player, answer string) types.Card {
+	if !isValidAnswer(deck.Choice, player, answer) {
+		return nil
+	}
+	return deck.resolveChoice(answer)
+}
+
+func (deck *Custom) resolveChoice(answer string) types.Card {
+	topCard := deck.getTopCard()
+	if topCard == nil {
+		return nil
+	}
+	player := deck.getPlayer(deck.ActiveIndex)
+	switch deck.Choice.Kind {
+	case types.ChoicePlayer:
+		if target := findPlayerByHex(deck.room, answer); target != nil && target != player {
+			player.Cards, target.Cards = target.Cards, player.Cards
+		}
+		// A wild swap card still needs its color
+		if topCard.Color == CustomWildColor {
+			deck.Choice = newColorChoice(player, deck.definition.Colors)
+			return topCard
+		}
+	case types.ChoiceColor:
+		topCard.Color = answer
+	}
+	deck.Choice = nil
+	deck.finishTurn()
+	return topCard
+}
+
+func (deck *Custom) TimeoutTurn() types.Card {
+	if deck.Choice != nil {
+		return deck.resolveChoice(randomAnswer(deck.Choice, deck.room.Random))
+	}
+	if deck.PassTurn() {
+		return nil
+	}
+	deck.drawCard(deck.getPlayer(deck.ActiveIndex))
+	deck.nextPlayer()
+	return nil
+}
+
+func (deck *Custom) GetCardPoints(card types.Card) int {
+	cardDefinition := deck.definition.getCard(card.(*CustomCard).Symbol)
+	if cardDefinition == nil {
+		return 0
+	}
+	return cardDefinition.Points
+}
+
+func (deck *Custom) GetPendingDraw() int {
+	return 0
+}
+
+func (deck *Custom) IsPlayerActive(target *types.Player) bool {
+	return deck.getPlayer(deck.ActiveIndex) == target
+}
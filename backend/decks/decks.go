@@ -1,39 +1,94 @@
 package decks
 
 import (
+	"sort"
+	"sync"
+
 	"github.com/HexCardGames/HexDeck/types"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-func DeckFromInterface(cardDeckId int, cardDeck bson.D) types.CardDeck {
-	bsonBytes, _ := bson.Marshal(cardDeck)
+// DeckDefinition describes a card deck that can be selected for a room.
+// NewDeck and NewCard must return pointers to empty values; they are used both
+// to create a fresh deck when a game starts and as decode targets when a room
+// is restored from the database.
+type DeckDefinition struct {
+	Id      int
+	Name    string
+	NewDeck func() types.CardDeck
+	NewCard func() types.Card
+}
+
+var registryMutex sync.RWMutex = sync.RWMutex{}
+var registry map[int]DeckDefinition = make(map[int]DeckDefinition)
 
-	switch cardDeckId {
-	case 0:
-		deck := Classic{}
-		bson.Unmarshal(bsonBytes, &deck)
-		return &deck
-	case 1:
-		deck := HexV1{}
-		bson.Unmarshal(bsonBytes, &deck)
-		return &deck
+// RegisterDeck adds a deck to the registry. It returns false if the ID is
+// already taken or the definition is incomplete.
+func RegisterDeck(definition DeckDefinition) bool {
+	if definition.NewDeck == nil || definition.NewCard == nil {
+		return false
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, exists := registry[definition.Id]; exists {
+		return false
 	}
+	registry[definition.Id] = definition
+	return true
+}
 
-	return nil
+func GetDeck(cardDeckId int) (DeckDefinition, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	definition, ok := registry[cardDeckId]
+	return definition, ok
 }
 
-func CardFromInterface(cardDeckId int, card bson.D) types.Card {
-	bsonBytes, _ := bson.Marshal(card)
+func IsValidDeckId(cardDeckId int) bool {
+	_, ok := GetDeck(cardDeckId)
+	return ok
+}
 
-	switch cardDeckId {
-	case 0:
-		deck := ClassicCard{}
-		bson.Unmarshal(bsonBytes, &deck)
-		return &deck
-	case 1:
-		deck := HexV1Card{}
-		bson.Unmarshal(bsonBytes, &deck)
-		return &deck
+// ListDecks returns all registered decks ordered by their ID
+func ListDecks() []DeckDefinition {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	definitions := make([]DeckDefinition, 0, len(registry))
+	for _, definition := range registry {
+		definitions = append(definitions, definition)
 	}
-	return nil
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Id < definitions[j].Id
+	})
+	return definitions
+}
+
+func NewDeck(cardDeckId int) types.CardDeck {
+	definition, ok := GetDeck(cardDeckId)
+	if !ok {
+		return nil
+	}
+	return definition.NewDeck()
+}
+
+func DeckFromInterface(cardDeckId int, cardDeck bson.D) types.CardDeck {
+	definition, ok := GetDeck(cardDeckId)
+	if !ok {
+		return nil
+	}
+	bsonBytes, _ := bson.Marshal(cardDeck)
+	deck := definition.NewDeck()
+	bson.Unmarshal(bsonBytes, deck)
+	return deck
+}
+
+func CardFromInterface(cardDeckId int, card bson.D) types.Card {
+	definition, ok := GetDeck(cardDeckId)
+	if !ok {
+		return nil
+	}
+	bsonBytes, _ := bson.Marshal(card)
+	deckCard := definition.NewCard()
+	bson.Unmarshal(bsonBytes, deckCard)
+	return deckCard
 }
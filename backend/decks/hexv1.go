@@ -2,32 +2,87 @@ package decks
 
 import (
 	"fmt"
-	"math/rand/v2"
+	"strings"
 
 	"github.com/HexCardGames/HexDeck/types"
 	"github.com/HexCardGames/HexDeck/utils"
 )
 
+const HexV1DeckId = 1
+
+func init() {
+	RegisterDeck(DeckDefinition{
+		Id:      HexV1DeckId,
+		Name:    "HexV1",
+		NewDeck: func() types.CardDeck { return &HexV1{} },
+		NewCard: func() types.Card { return &HexV1Card{} },
+	})
+}
+
 type HexV1 struct {
 	room        *types.Room
 	CardsPlayed []*HexV1Card
-	PlayerOrder []int
-	ActiveIndex int
+	// Shuffled draw pile, only used with the finite deck option
+	CardsRemaining []*HexV1Card
+	PlayerOrder    []int
+	ActiveIndex    int
+	// See Classic.DrawnCard
+	DrawnCard *HexV1Card
+	// Decision the game waits for, like the target of a swap card
+	Choice *types.PendingChoice
 }
 
 type HexV1Card struct {
 	Symbol       string
 	Color        string
 	NumericValue int
+	// Rainbow stays set after the color of a rainbow card was chosen
+	Rainbow bool
+}
+
+// reset clears the color chosen for a rainbow card
+func (card *HexV1Card) reset() {
+	if card.Rainbow {
+		card.Color = "rainbow"
+	}
 }
 
 var HexV1Colors = []string{"blue", "green", "yellow", "purple"}
 var HexV1ActionCards = []string{"shuffle", "skip", "draw", "swap"}
 
+// fillDeck creates the draw pile of the finite deck: every hex value and
+// action card in each color plus one rainbow card per action
+func (deck *HexV1) fillDeck() {
+	cards := make([]*HexV1Card, 0, len(HexV1Colors)*(16+len(HexV1ActionCards))+len(HexV1ActionCards))
+	for _, color := range HexV1Colors {
+		for value := 0; value < 16; value++ {
+			cards = append(cards, &HexV1Card{
+				Symbol:       fmt.Sprintf("%x", value),
+				Color:        color,
+				NumericValue: value,
+			})
+		}
+		for _, action := range HexV1ActionCards {
+			cards = append(cards, &HexV1Card{Symbol: "action:" + action, Color: color, NumericValue: 3})
+		}
+	}
+	for _, action := range HexV1ActionCards {
+		cards = append(cards, &HexV1Card{Symbol: "action:" + action, Color: "rainbow", NumericValue: 3, Rainbow: true})
+	}
+	utils.ShuffleSlice(&cards, deck.room.Random)
+	deck.CardsRemaining = cards
+}
+
 func (deck *HexV1) Init(room *types.Room) {
 	deck.room = room
 	deck.PlayerOrder = make([]int, len(room.Players))
 	deck.ActiveIndex = 0
+	deck.DrawnCard = nil
+	deck.Choice = nil
+	deck.CardsRemaining = nil
+	if room.GameOptions.FiniteHexDeck {
+		deck.fillDeck()
+	}
 
 	deck.room.PlayersMutex.Lock()
 	defer deck.room.PlayersMutex.Unlock()
@@ -41,17 +96,32 @@ func (deck *HexV1) Init(room *types.Room) {
 
 func (deck *HexV1) SetRoom(room *types.Room) {
 	deck.room = room
+	deck.DrawnCard = relinkCard(deck.DrawnCard, deck.getPlayer(deck.ActiveIndex))
+	deck.restoreColorChoice()
+}
+
+// restoreColorChoice asks the active player for a color again if a room was
+// stored with an uncolored rainbow card before pending choices existed
+func (deck *HexV1) restoreColorChoice() {
+	topCard := deck.getTopCard()
+	player := deck.getPlayer(deck.ActiveIndex)
+	if deck.Choice != nil || topCard == nil || topCard.Color != "rainbow" || player == nil {
+		return
+	}
+	deck.Choice = newColorChoice(player, HexV1Colors)
 }
 
+// IsEmpty reports whether no card can be drawn. Cards are generated endlessly
+// unless the finite deck option is set.
 func (deck *HexV1) IsEmpty() bool {
-	return false
+	if !deck.room.GameOptions.FiniteHexDeck {
+		return false
+	}
+	return isPileEmpty(&deck.CardsPlayed, &deck.CardsRemaining, deck.room.Random, (*HexV1Card).reset)
 }
 
 func (deck *HexV1) getTopCard() *HexV1Card {
-	if len(deck.CardsPlayed) == 0 {
-		return nil
-	}
-	return deck.CardsPlayed[len(deck.CardsPlayed)-1]
+	return getTopCard(deck.CardsPlayed)
 }
 
 func (deck *HexV1) GetTopCard() types.Card {
@@ -59,8 +129,8 @@ func (deck *HexV1) GetTopCard() types.Card {
 }
 
 func (deck *HexV1) generateCard() *HexV1Card {
-	cardType := rand.IntN(16 + len(HexV1ActionCards))
-	cardColor := HexV1Colors[rand.IntN(len(HexV1Colors))]
+	cardType := deck.room.Random.IntN(16 + len(HexV1ActionCards))
+	cardColor := HexV1Colors[deck.room.Random.IntN(len(HexV1Colors))]
 	if cardType < 16 {
 		return &HexV1Card{
 			Symbol:       fmt.Sprintf("%x", cardType),
@@ -69,20 +139,27 @@ func (deck *HexV1) generateCard() *HexV1Card {
 		}
 	}
 	cardSymbol := HexV1ActionCards[cardType-16]
-	if rand.IntN(100) <= 10 {
+	if deck.room.Random.IntN(100) <= 10 {
 		cardColor = "rainbow"
 	}
 	return &HexV1Card{
 		Symbol:       "action:" + cardSymbol,
 		Color:        cardColor,
 		NumericValue: 3,
+		Rainbow:      cardColor == "rainbow",
 	}
 }
 
 func (deck *HexV1) drawCard(player *types.Player) types.Card {
-	card := deck.generateCard()
-	player.Cards = append(player.Cards, card)
-	return card
+	if !deck.room.GameOptions.FiniteHexDeck {
+		card := deck.generateCard()
+		player.Cards = append(player.Cards, card)
+		return card
+	}
+	if deck.IsEmpty() {
+		return nil
+	}
+	return takeCard(&deck.CardsRemaining, player)
 }
 
 func (deck *HexV1) drawMany(player *types.Player, cards int) {
@@ -91,35 +168,62 @@ func (deck *HexV1) drawMany(player *types.Player, cards int) {
 	}
 }
 
-func (deck *HexV1) DrawCard() types.Card {
+func (deck *HexV1) DrawCard() (types.Card, error) {
 	// Can't draw another card before wildcard color is selected
 	topCard := deck.getTopCard()
 	if topCard != nil && topCard.Color == "rainbow" {
-		return nil
+		return nil, types.ErrDrawNotAllowed
+	}
+	if deck.DrawnCard != nil || deck.Choice != nil {
+		return nil, types.ErrDrawNotAllowed
 	}
 
 	card := deck.drawCard(deck.getPlayer(deck.ActiveIndex))
+	if card == nil {
+		deck.nextPlayer()
+		return nil, types.ErrDeckExhausted
+	}
+	if deck.CanPlay(card) {
+		deck.DrawnCard = card.(*HexV1Card)
+		return card, nil
+	}
 	deck.nextPlayer()
-	return card
+	return card, nil
 }
 
-func (deck *HexV1) getNextValidIndex(index int) int {
-	if len(deck.room.Players) == 0 || len(deck.PlayerOrder) == 0 {
-		return -1
+func (deck *HexV1) PassTurn() bool {
+	if deck.DrawnCard == nil {
+		return false
 	}
-	checkIndex := utils.Mod(index, len(deck.PlayerOrder))
-	for deck.PlayerOrder[checkIndex] >= len(deck.room.Players) {
-		checkIndex = utils.Mod(checkIndex+1, len(deck.PlayerOrder))
+	deck.DrawnCard = nil
+	deck.nextPlayer()
+	return true
+}
+
+func (deck *HexV1) SkipTurn() bool {
+	topCard := deck.getTopCard()
+	if deck.Choice != nil || (topCard != nil && topCard.Color == "rainbow") {
+		return false
 	}
-	return checkIndex
+	deck.DrawnCard = nil
+	deck.nextPlayer()
+	return true
+}
+
+func (deck *HexV1) DrawCards(player *types.Player, amount int) {
+	deck.drawMany(player, amount)
+}
+
+func (deck *HexV1) getSeatedPlayer(seat int) *types.Player {
+	return getSeatedPlayer(deck.room, deck.PlayerOrder, seat)
+}
+
+func (deck *HexV1) getNextValidIndex(index int) int {
+	return findNextSeat(deck.room, len(deck.PlayerOrder), index, 1, deck.getSeatedPlayer)
 }
 
 func (deck *HexV1) getPlayer(index int) *types.Player {
-	playerIndex := deck.getNextValidIndex(index)
-	if playerIndex == -1 {
-		return nil
-	}
-	return deck.room.Players[deck.PlayerOrder[playerIndex]]
+	return deck.getSeatedPlayer(deck.getNextValidIndex(index))
 }
 
 func (deck *HexV1) getNextPlayerIndex() int {
@@ -130,6 +234,14 @@ func (deck *HexV1) nextPlayer() {
 	deck.ActiveIndex = deck.getNextPlayerIndex()
 }
 
+func (deck *HexV1) GetCardPoints(card types.Card) int {
+	return card.(*HexV1Card).NumericValue
+}
+
+func (deck *HexV1) GetPendingDraw() int {
+	return 0
+}
+
 func (deck *HexV1) IsPlayerActive(target *types.Player) bool {
 	return deck.getPlayer(deck.ActiveIndex) == target
 }
@@ -140,6 +252,9 @@ func (deck *HexV1) CanPlay(card types.Card) bool {
 	if topCard == nil || checkCard == nil {
 		return topCard == nil
 	}
+	if deck.Choice != nil || (deck.DrawnCard != nil && checkCard != deck.DrawnCard) {
+		return false
+	}
 	return topCard.Color != "rainbow" && (checkCard.Color == "rainbow" || checkCard.Color == topCard.Color || checkCard.Symbol == topCard.Symbol)
 }
 
@@ -148,6 +263,7 @@ func (deck *HexV1) PlayCard(card types.Card) bool {
 		return false
 	}
 	deckCard := card.(*HexV1Card)
+	deck.DrawnCard = nil
 	targetPlayer := deck.getPlayer(deck.ActiveIndex)
 	nextPlayer := deck.getPlayer(deck.getNextPlayerIndex())
 
@@ -161,15 +277,15 @@ func (deck *HexV1) PlayCard(card types.Card) bool {
 		}
 		deck.drawMany(nextPlayer, amount)
 	} else if deckCard.Symbol == "action:shuffle" {
-		utils.ShuffleSlice(&deck.PlayerOrder)
+		utils.ShuffleSlice(&deck.PlayerOrder, deck.room.Random)
 	} else if deckCard.Symbol == "action:swap" {
-		p1Cards := targetPlayer.Cards
-		p2Cards := nextPlayer.Cards
-		targetPlayer.Cards = p2Cards
-		nextPlayer.Cards = p1Cards
+		deck.Choice = newSwapChoice(deck.room, targetPlayer)
 	}
 
-	if deckCard.Color != "rainbow" {
+	if deckCard.Color == "rainbow" && deck.Choice == nil {
+		deck.Choice = newColorChoice(targetPlayer, HexV1Colors)
+	}
+	if deck.Choice == nil {
 		deck.nextPlayer()
 	}
 
@@ -177,29 +293,106 @@ func (deck *HexV1) PlayCard(card types.Card) bool {
 	return true
 }
 
-func (deck *HexV1) UpdatePlayedCard(cardData interface{}) types.Card {
+func (deck *HexV1) CanJumpIn(player *types.Player, card types.Card) bool {
 	topCard := deck.getTopCard()
-	if topCard.Color != "rainbow" {
-		return nil
+	checkCard := card.(*HexV1Card)
+	if !deck.room.GameOptions.JumpIn || topCard == nil || topCard.Color == "rainbow" || deck.Choice != nil {
+		return false
+	}
+	return *checkCard == *topCard && getSeat(deck.room, deck.PlayerOrder, player) != -1 && !deck.IsPlayerActive(player)
+}
+
+func (deck *HexV1) JumpIn(player *types.Player, card types.Card) bool {
+	if !deck.CanJumpIn(player, card) {
+		return false
+	}
+	deck.ActiveIndex = getSeat(deck.room, deck.PlayerOrder, player)
+	deck.DrawnCard = nil
+	return deck.PlayCard(card)
+}
+
+// CanPlayCards allows a combo of number cards whose values sum up to the value
+// of the top card in hex, ignoring any overflow
+func (deck *HexV1) CanPlayCards(cards []types.Card) bool {
+	if len(cards) == 1 {
+		return deck.CanPlay(cards[0])
+	}
+	topCard := deck.getTopCard()
+	if len(cards) == 0 || topCard == nil || topCard.Color == "rainbow" || strings.HasPrefix(topCard.Symbol, "action:") || deck.Choice != nil || deck.DrawnCard != nil {
+		return false
+	}
+	sum := 0
+	for _, card := range cards {
+		deckCard := card.(*HexV1Card)
+		if strings.HasPrefix(deckCard.Symbol, "action:") {
+			return false
+		}
+		sum += deckCard.NumericValue
+	}
+	return sum%16 == topCard.NumericValue
+}
+
+func (deck *HexV1) PlayCards(cards []types.Card) bool {
+	if !deck.CanPlayCards(cards) {
+		return false
 	}
-	updateData, ok := cardData.(map[string]interface{})
-	if !ok {
+	if len(cards) == 1 {
+		return deck.PlayCard(cards[0])
+	}
+	for _, card := range cards {
+		deck.CardsPlayed = append(deck.CardsPlayed, card.(*HexV1Card))
+	}
+	deck.nextPlayer()
+	return true
+}
+
+func (deck *HexV1) TimeoutTurn() types.Card {
+	if deck.Choice != nil {
+		return deck.resolveChoice(randomAnswer(deck.Choice, deck.room.Random))
+	}
+	if deck.PassTurn() {
 		return nil
 	}
-	newColor, ok := updateData["Color"].(string)
-	if !ok {
+	deck.drawCard(deck.getPlayer(deck.ActiveIndex))
+	deck.nextPlayer()
+	return nil
+}
+
+func (deck *HexV1) GetPendingChoice() *types.PendingChoice {
+	return deck.Choice
+}
+
+func (deck *HexV1) ResolveChoice(player *types.Player, answer string) types.Card {
+	if !isValidAnswer(deck.Choice, player, answer) {
 		return nil
 	}
+	return deck.resolveChoice(answer)
+}
 
-	for _, color := range HexV1Colors {
-		if newColor == color {
-			deck.nextPlayer()
-			if topCard.Symbol == "action:skip" {
-				deck.nextPlayer()
-			}
-			topCard.Color = color
+func (deck *HexV1) resolveChoice(answer string) types.Card {
+	topCard := deck.getTopCard()
+	if topCard == nil {
+		return nil
+	}
+	switch deck.Choice.Kind {
+	case types.ChoicePlayer:
+		player := deck.getPlayer(deck.ActiveIndex)
+		if target := findPlayerByHex(deck.room, answer); target != nil && target != player {
+			player.Cards, target.Cards = target.Cards, player.Cards
+		}
+		// A rainbow swap card still needs its color
+		if topCard.Color == "rainbow" {
+			deck.Choice = newColorChoice(player, HexV1Colors)
 			return topCard
 		}
+		deck.nextPlayer()
+	case types.ChoiceColor:
+		deck.nextPlayer()
+		if topCard.Symbol == "action:skip" {
+			deck.nextPlayer()
+		}
+		topCard.Color = answer
 	}
-	return nil
+	deck.Choice = nil
+	return topCard
 }
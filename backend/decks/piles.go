@@ -0,0 +1,118 @@
+package decks
+
+import (
+	"github.com/HexCardGames/HexDeck/types"
+	"github.com/HexCardGames/HexDeck/utils"
+)
+
+// Helpers for the card piles and turn order shared by all decks. Each deck
+// keeps its own typed fields, so the stored room layout stays unchanged.
+
+// startingHandSize returns the number of cards dealt to every player
+func startingHandSize(room *types.Room) int {
+	if room.GameOptions.StartingHandSize < 1 {
+		// Rooms created before game options existed don't have a hand size set
+		return types.DefaultGameOptions().StartingHandSize
+	}
+	return room.GameOptions.StartingHandSize
+}
+
+func getTopCard[T any](played []*T) *T {
+	if len(played) == 0 {
+		return nil
+	}
+	return played[len(played)-1]
+}
+
+// isPileEmpty reports whether no card can be drawn. An empty draw pile is
+// refilled from the discard pile first, so this is only true once both piles
+// are empty.
+func isPileEmpty[T any](played *[]*T, remaining *[]*T, random *utils.Random, reset func(*T)) bool {
+	if len(*remaining) == 0 {
+		recyclePlayedCards(played, remaining, random, reset)
+	}
+	return len(*remaining) == 0
+}
+
+// recyclePlayedCards shuffles all played cards except the top card back into
+// the draw pile. reset is called for every recycled card, so wild cards lose
+// the color chosen when they were played.
+func recyclePlayedCards[T any](played *[]*T, remaining *[]*T, random *utils.Random, reset func(*T)) {
+	if len(*played) <= 1 {
+		return
+	}
+	topCard := getTopCard(*played)
+	cards := (*played)[:len(*played)-1]
+	for _, card := range cards {
+		reset(card)
+	}
+	utils.ShuffleSlice(&cards, random)
+	*remaining = append(*remaining, cards...)
+	*played = []*T{topCard}
+}
+
+// takeCard moves the first card of a non-empty draw pile into the player's hand
+func takeCard[T any](remaining *[]*T, player *types.Player) *T {
+	card := (*remaining)[0]
+	*remaining = (*remaining)[1:]
+	player.Cards = append(player.Cards, any(card).(types.Card))
+	return card
+}
+
+// relinkCard returns the card in the player's hand that equals the given card,
+// or nil if there is none. A deck's DrawnCard has to point into the active
+// player's hand, but both are decoded separately when a room is restored.
+func relinkCard[T comparable](card *T, player *types.Player) *T {
+	if card == nil || player == nil {
+		return nil
+	}
+	for i := len(player.Cards) - 1; i >= 0; i-- {
+		if handCard, ok := player.Cards[i].(*T); ok && *handCard == *card {
+			return handCard
+		}
+	}
+	return nil
+}
+
+// getSeatedPlayer returns the player at the seat of a shuffled turn order, or
+// nil if they left the room
+func getSeatedPlayer(room *types.Room, order []int, seat int) *types.Player {
+	if seat < 0 || seat >= len(order) || order[seat] >= len(room.Players) {
+		return nil
+	}
+	return room.Players[order[seat]]
+}
+
+// getSeat returns the seat of the player in a shuffled turn order, or -1
+func getSeat(room *types.Room, order []int, target *types.Player) int {
+	for seat := range order {
+		if getSeatedPlayer(room, order, seat) == target {
+			return seat
+		}
+	}
+	return -1
+}
+
+// findNextSeat returns the first of the seats, starting at seat and moving in
+// the given direction, whose player is still in the game. playerAt returns nil
+// for seats of players who left the room. If everyone finished, it falls back
+// to any player still in the room, and it returns -1 if there is nobody.
+func findNextSeat(room *types.Room, seats int, seat int, direction int, playerAt func(int) *types.Player) int {
+	if seats == 0 || len(room.Players) == 0 {
+		return -1
+	}
+	fallback := -1
+	seat = utils.Mod(seat, seats)
+	for range seats {
+		if player := playerAt(seat); player != nil {
+			if !room.HasFinished(player) {
+				return seat
+			}
+			if fallback == -1 {
+				fallback = seat
+			}
+		}
+		seat = utils.Mod(seat+direction, seats)
+	}
+	return fallback
+}
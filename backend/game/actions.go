@@ -0,0 +1,324 @@
+package game
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/HexCardGames/HexDeck/types"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// The functions in this file implement the game actions shared by connected
+// players and bots. The caller has to hold the player's mutex. They return nil
+// on success and otherwise the status that should be sent to the player.
+
+func verifyGameRunning(room *types.Room) *types.S2C_Status {
+	if room.GameState != types.StateRunning {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "game_not_running",
+			Message:    "The game is not running",
+		}
+	}
+
+	if room.Paused {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "game_paused",
+			Message:    "The game is paused",
+		}
+	}
+
+	if room.NextRoundIn > 0 {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "round_ended",
+			Message:    "The round has ended, wait for the next round to start",
+		}
+	}
+	return nil
+}
+
+func verifyActivePlayer(room *types.Room, target *types.Player) *types.S2C_Status {
+	if status := verifyGameRunning(room); status != nil {
+		return status
+	}
+	if !room.CardDeck.IsPlayerActive(target) {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "player_not_active",
+			Message:    "You can't execute this action while you are not the active player",
+		}
+	}
+	return nil
+}
+
+func DrawCard(room *types.Room, player *types.Player) *types.S2C_Status {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if status := verifyActivePlayer(room, player); status != nil {
+		return status
+	}
+	var status *types.S2C_Status
+	handSize := len(player.Cards)
+	_, err := room.CardDeck.DrawCard()
+	if errors.Is(err, types.ErrDrawNotAllowed) {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "card_not_drawable",
+			Message:    "You can't draw a card now",
+		}
+	}
+	if errors.Is(err, types.ErrDeckExhausted) {
+		status = &types.S2C_Status{
+			IsError:    false,
+			StatusCode: "deck_exhausted",
+			Message:    "There are no cards left to draw, your turn was passed",
+		}
+	}
+	LogEvent(room, types.EventCardsDrawn, player, types.EventDataCardsDrawn{Cards: drawnCards(player, handSize)})
+	player.DeclaredLastCard = false
+	UpdateLastCardState(room, player)
+	UpdateAllPlayers(room)
+	return status
+}
+
+func PassTurn(room *types.Room, player *types.Player) *types.S2C_Status {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if status := verifyActivePlayer(room, player); status != nil {
+		return status
+	}
+	if !room.CardDeck.PassTurn() {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "cannot_pass_turn",
+			Message:    "You can only pass your turn after drawing a playable card",
+		}
+	}
+	LogEvent(room, types.EventTurnPassed, player, nil)
+	UpdateLastCardState(room, player)
+	UpdateAllPlayers(room)
+	return nil
+}
+
+func ChallengeDrawFour(room *types.Room, player *types.Player) *types.S2C_Status {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if status := verifyActivePlayer(room, player); status != nil {
+		return status
+	}
+	challengeDeck, ok := room.CardDeck.(types.DrawFourChallenger)
+	var result *types.ChallengeResult
+	if ok {
+		result = challengeDeck.ChallengeDrawFour()
+	}
+	if result == nil {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "cannot_challenge",
+			Message:    "There is no Wild Draw Four you can challenge",
+		}
+	}
+	LogEvent(room, types.EventDrawFourChallenged, result.Challenged, types.EventDataChallenge{
+		ChallengedBy: player.PlayerId,
+		Successful:   result.Successful,
+		Penalty:      result.Penalty,
+	})
+	BroadcastInRoom(room, "ChallengeResult", types.BuildChallengeResultPacket(result))
+	UpdateLastCardState(room, player)
+	UpdateAllPlayers(room)
+	return nil
+}
+
+func PlayCard(room *types.Room, player *types.Player, cardIndex int) *types.S2C_Status {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	status := verifyActivePlayer(room, player)
+	if status != nil && status.StatusCode != "player_not_active" {
+		return status
+	}
+	if cardIndex < 0 || cardIndex >= len(player.Cards) {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "invalid_card_index",
+			Message:    "Provided CardIndex is out of bounds",
+		}
+	}
+	card := player.Cards[cardIndex]
+
+	// Players who aren't active can only jump in with a card identical to the top card
+	jumpIn := status != nil
+	jumpInDeck, ok := room.CardDeck.(types.JumpInDeck)
+	if jumpIn && (!ok || !room.GameOptions.JumpIn || !jumpInDeck.CanJumpIn(player, card)) {
+		return status
+	}
+	if !jumpIn && !room.CardDeck.CanPlay(card) {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "card_not_playable",
+			Message:    "You can't play this card now",
+		}
+	}
+	player.Cards = append(player.Cards[:cardIndex], player.Cards[cardIndex+1:]...)
+	if jumpIn {
+		ok = jumpInDeck.JumpIn(player, card)
+	} else {
+		ok = room.CardDeck.PlayCard(card)
+	}
+	if !ok {
+		slog.Error("Cannot play card after checking", "roomId", room.RoomId.Hex(), "playerId", player.PlayerId.Hex())
+	}
+	LogEvent(room, types.EventCardPlayed, player, types.EventDataCardPlayed{Card: card, CardIndex: cardIndex, JumpIn: jumpIn})
+	UpdateLastCardState(room, player)
+	OnPlayCard(room, player, cardIndex, card)
+
+	if len(player.Cards) == 0 {
+		OnPlayerFinished(room, player)
+	}
+	return nil
+}
+
+// PassCardToPartner gives a card of the active player to a teammate, which
+// uses up their turn
+func PassCardToPartner(room *types.Room, player *types.Player, cardIndex int, partnerId bson.ObjectID) *types.S2C_Status {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if status := verifyActivePlayer(room, player); status != nil {
+		return status
+	}
+	if !isTeamMode(room) || !room.GameOptions.PassToPartner {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "passing_disabled",
+			Message:    "Passing cards to your partner is not allowed in this game",
+		}
+	}
+	var partner *types.Player
+	for _, roomPlayer := range room.Players {
+		if roomPlayer.PlayerId == partnerId && isPartner(player, roomPlayer) {
+			partner = roomPlayer
+		}
+	}
+	if partner == nil {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "invalid_partner",
+			Message:    "You can only pass cards to a player of your team",
+		}
+	}
+	if cardIndex < 0 || cardIndex >= len(player.Cards) {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "invalid_card_index",
+			Message:    "Provided CardIndex is out of bounds",
+		}
+	}
+	if len(player.Cards) == 1 || !room.CardDeck.SkipTurn() {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "cannot_pass_card",
+			Message:    "You can't pass a card to your partner now",
+		}
+	}
+
+	card := player.Cards[cardIndex]
+	player.Cards = append(player.Cards[:cardIndex], player.Cards[cardIndex+1:]...)
+	partner.Cards = append(partner.Cards, card)
+	LogEvent(room, types.EventCardPassed, player, types.EventDataCardPassed{Card: card, CardIndex: cardIndex, PassedTo: partner.PlayerId})
+	UpdateLastCardState(room, player)
+	UpdateAllPlayers(room)
+	return nil
+}
+
+// PlayCards plays several cards of the player in a single move, if the game
+// options and the card deck allow it
+func PlayCards(room *types.Room, player *types.Player, cardIndices []int) *types.S2C_Status {
+	if len(cardIndices) == 1 {
+		return PlayCard(room, player, cardIndices[0])
+	}
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if status := verifyActivePlayer(room, player); status != nil {
+		return status
+	}
+	multiCardDeck, ok := room.CardDeck.(types.MultiCardDeck)
+	if !ok || !room.GameOptions.MultiCardPlays {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "multi_card_plays_disabled",
+			Message:    "You can only play a single card per move",
+		}
+	}
+
+	cards := make([]types.Card, len(cardIndices))
+	selected := make(map[int]bool)
+	for i, cardIndex := range cardIndices {
+		if cardIndex < 0 || cardIndex >= len(player.Cards) || selected[cardIndex] {
+			return &types.S2C_Status{
+				IsError:    true,
+				StatusCode: "invalid_card_index",
+				Message:    "Provided CardIndices are out of bounds or contain duplicates",
+			}
+		}
+		selected[cardIndex] = true
+		cards[i] = player.Cards[cardIndex]
+	}
+	if !multiCardDeck.CanPlayCards(cards) {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "cards_not_playable",
+			Message:    "You can't play this combination of cards now",
+		}
+	}
+
+	remainingCards := make([]types.Card, 0, len(player.Cards)-len(cards))
+	for i, card := range player.Cards {
+		if !selected[i] {
+			remainingCards = append(remainingCards, card)
+		}
+	}
+	player.Cards = remainingCards
+	if !multiCardDeck.PlayCards(cards) {
+		slog.Error("Cannot play cards after checking", "roomId", room.RoomId.Hex(), "playerId", player.PlayerId.Hex())
+	}
+	LogEvent(room, types.EventCardsPlayed, player, types.EventDataCardsPlayed{Cards: cards, CardIndices: cardIndices})
+	UpdateLastCardState(room, player)
+	OnPlayCards(room, player, cardIndices, cards)
+
+	if len(player.Cards) == 0 {
+		OnPlayerFinished(room, player)
+	}
+	return nil
+}
+
+// ResolveChoice answers the decision the game is waiting for. The choosing
+// player doesn't have to be the active player.
+func ResolveChoice(room *types.Room, player *types.Player, answer string) *types.S2C_Status {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if status := verifyGameRunning(room); status != nil {
+		return status
+	}
+	choice := room.CardDeck.GetPendingChoice()
+	if choice == nil || choice.PlayerId != player.PlayerId {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "no_pending_choice",
+			Message:    "There is no decision waiting for you",
+		}
+	}
+	kind := choice.Kind
+	card := room.CardDeck.ResolveChoice(player, answer)
+	if card == nil {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "invalid_choice",
+			Message:    "The provided answer is not one of the valid options",
+		}
+	}
+	LogEvent(room, types.EventCardUpdated, player, types.EventDataCardUpdated{Card: card, Kind: kind, Answer: answer})
+	UpdateLastCardState(room, player)
+	OnPlayedCardUpdate(room, player, card)
+	return nil
+}
@@ -0,0 +1,109 @@
+package game
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/HexCardGames/HexDeck/bots"
+	"github.com/HexCardGames/HexDeck/types"
+	petname "github.com/dustinkirkland/golang-petname"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Time in milliseconds a bot waits before taking its turn
+const botMoveDelay = 1500
+
+var botStrategy bots.Strategy = bots.SimpleStrategy{}
+
+func AddBot(room *types.Room) *types.Player {
+	username := petname.Generate(2, " ")
+	for !room.IsUsernameAvailable(username) {
+		username = petname.Generate(2, " ")
+	}
+
+	bot := &types.Player{
+		PlayerId:    bson.NewObjectID(),
+		Username:    username,
+		Permissions: 0,
+		IsBot:       true,
+		Cards:       make([]types.Card, 0),
+		Connection: types.WebsocketConnection{
+			IsConnected: false,
+		},
+		Mutex: &sync.Mutex{},
+	}
+	room.AppendPlayer(bot)
+	LogEvent(room, types.EventPlayerJoined, bot, nil)
+	OnRoomUpdate(room)
+	return bot
+}
+
+// findActingBot returns the bot the game is waiting for, or nil if it is
+// waiting for a human
+func findActingBot(room *types.Room) *types.Player {
+	if room.GameState != types.StateRunning || room.CardDeck == nil {
+		return nil
+	}
+	// A bot that finished on a wildcard still owns the pending choice, even
+	// though it already left the turn order
+	if choice := room.CardDeck.GetPendingChoice(); choice != nil {
+		for _, player := range room.Players {
+			if player.PlayerId == choice.PlayerId && player.IsBot {
+				return player
+			}
+		}
+	}
+	if activePlayer := findActivePlayer(room); activePlayer != nil && activePlayer.IsBot {
+		return activePlayer
+	}
+	return nil
+}
+
+func tickBots(room *types.Room, deltaTime int) {
+	bot := findActingBot(room)
+	if bot == nil || room.NextRoundIn > 0 || room.Paused {
+		room.BotTimeRemaining = botMoveDelay
+		return
+	}
+	room.BotTimeRemaining -= deltaTime
+	if room.BotTimeRemaining > 0 {
+		return
+	}
+	room.BotTimeRemaining = botMoveDelay
+
+	bot.Mutex.Lock()
+	defer bot.Mutex.Unlock()
+	playBotTurn(room, bot)
+}
+
+// playBotTurn executes a single action for the bot, so a wildcard color is
+// chosen on the following tick
+func playBotTurn(room *types.Room, bot *types.Player) {
+	if len(bot.Cards) <= 2 && !bot.DeclaredLastCard {
+		DeclareLastCard(room, bot)
+	}
+
+	if choice := room.CardDeck.GetPendingChoice(); choice != nil && choice.PlayerId == bot.PlayerId {
+		answer := bots.ChooseAnswer(botStrategy, bot, room.Players, choice)
+		if ResolveChoice(room, bot, answer) == nil {
+			return
+		}
+	}
+
+	cardIndex := botStrategy.ChooseCard(bot.Cards, room.CardDeck.GetTopCard(), room.CardDeck.CanPlay)
+	if cardIndex >= 0 && PlayCard(room, bot, cardIndex) == nil {
+		return
+	}
+	if status := DrawCard(room, bot); status == nil || !status.IsError {
+		return
+	}
+
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	// A human may have jumped in since the bot's turn started
+	if verifyActivePlayer(room, bot) != nil {
+		return
+	}
+	slog.Debug("Bot could not find a valid action, applying default action", "playerId", bot.PlayerId.Hex(), "roomId", room.RoomId.Hex())
+	applyDefaultAction(room, bot)
+}
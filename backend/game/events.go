@@ -0,0 +1,49 @@
+package game
+
+import (
+	"time"
+
+	"github.com/HexCardGames/HexDeck/db"
+	"github.com/HexCardGames/HexDeck/types"
+)
+
+// LogEvent appends an event to the room's event log. player may be nil for
+// events that weren't caused by a specific player.
+func LogEvent(room *types.Room, eventType types.EventType, player *types.Player, data interface{}) {
+	event := types.GameEvent{
+		RoomId:    room.RoomId,
+		Sequence:  room.EventSequence,
+		Timestamp: time.Now(),
+		Type:      eventType,
+		Data:      data,
+	}
+	if player != nil {
+		event.PlayerId = &player.PlayerId
+	}
+	room.EventSequence += 1
+	db.Conn.InsertEvent(event)
+}
+
+func buildRoundStartedEventData(room *types.Room) types.EventDataRoundStarted {
+	hands := make([]types.EventDataHand, len(room.Players))
+	for i, player := range room.Players {
+		hands[i] = types.EventDataHand{PlayerId: player.PlayerId, Cards: player.Cards}
+	}
+	return types.EventDataRoundStarted{
+		Round:       room.Round,
+		Seed:        room.Seed,
+		CardDeckId:  room.CardDeckId,
+		GameOptions: room.GameOptions,
+		Hands:       hands,
+	}
+}
+
+// drawnCards returns a copy of the cards the player received since their hand had the given size
+func drawnCards(player *types.Player, handSize int) []types.Card {
+	if len(player.Cards) <= handSize {
+		return []types.Card{}
+	}
+	cards := make([]types.Card, len(player.Cards)-handSize)
+	copy(cards, player.Cards[handSize:])
+	return cards
+}
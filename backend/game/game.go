@@ -18,33 +18,56 @@ import (
 var roomsMutex sync.Mutex = sync.Mutex{}
 var rooms []*types.Room = make([]*types.Room, 0)
 
+// GenerateJoinCode returns a join code that isn't used by any room. It doesn't
+// use the room's seeded generator, so codes can't be predicted from a seed.
+// The caller must hold roomsMutex.
 func GenerateJoinCode() string {
-	code := ""
-	for i := 0; i < 6; i++ {
-		code += strconv.Itoa(rand.IntN(10))
+	for {
+		code := ""
+		for i := 0; i < 6; i++ {
+			code += strconv.Itoa(rand.IntN(10))
+		}
+		if FindRoomByJoinCode(code) == nil {
+			return code
+		}
 	}
-	return code
 }
 
 func LoadRooms() {
 	roomsMutex.Lock()
 	defer roomsMutex.Unlock()
-	rooms = db.Conn.QueryRunningRooms()
+	rooms = make([]*types.Room, 0)
+	for _, room := range db.Conn.QueryRunningRooms() {
+		// Custom decks may have been removed or failed to load since the room was stored
+		if !decks.IsValidDeckId(room.CardDeckId) {
+			slog.Error("Ending room with unknown card deck", "roomId", room.RoomId.Hex(), "cardDeckId", room.CardDeckId)
+			room.GameState = types.StateEnded
+			db.Conn.UpdateRoom(room)
+			continue
+		}
+		rooms = append(rooms, room)
+	}
 }
 
 func CreateRoom() *types.Room {
+	roomsMutex.Lock()
+	defer roomsMutex.Unlock()
+	seed := utils.NewSeed()
 	newRoom := &types.Room{
 		RoomId:       bson.NewObjectID(),
 		JoinCode:     GenerateJoinCode(),
+		Seed:         seed,
+		Random:       utils.NewRandom(seed),
 		GameState:    types.StateLobby,
 		Players:      make([]*types.Player, 0),
+		Spectators:   make([]*types.Player, 0),
 		PlayersMutex: &sync.Mutex{},
-		CardDeckId:   1,
+		ActionMutex:  &sync.Mutex{},
+		CardDeckId:   decks.HexV1DeckId,
+		GameOptions:  types.DefaultGameOptions(),
 	}
 
 	db.Conn.InsertRoom(newRoom)
-	roomsMutex.Lock()
-	defer roomsMutex.Unlock()
 	rooms = append(rooms, newRoom)
 	return newRoom
 }
@@ -59,18 +82,52 @@ func FindRoomByJoinCode(joinCode string) *types.Room {
 	return nil
 }
 
+func FindRoomById(roomId bson.ObjectID) *types.Room {
+	for _, room := range rooms {
+		if room.RoomId == roomId {
+			return room
+		}
+	}
+	return nil
+}
+
 func FindSession(sessionToken string) (*types.Room, *types.Player) {
+	// Bots don't have a session
+	if sessionToken == "" {
+		return nil, nil
+	}
 	for _, room := range rooms {
 		for _, player := range room.Players {
 			if player.SessionToken == sessionToken {
 				return room, player
 			}
 		}
+		for _, spectator := range room.Spectators {
+			if spectator.SessionToken == sessionToken {
+				return room, spectator
+			}
+		}
 	}
 	return nil, nil
 }
 
 func JoinRoom(room *types.Room, requestedUsername string) *types.Player {
+	player := createPlayer(room, requestedUsername)
+	room.AppendPlayer(player)
+	LogEvent(room, types.EventPlayerJoined, player, nil)
+	OnRoomUpdate(room)
+	return player
+}
+
+func SpectateRoom(room *types.Room, requestedUsername string) *types.Player {
+	spectator := createPlayer(room, requestedUsername)
+	room.AppendSpectator(spectator)
+	LogEvent(room, types.EventSpectatorJoined, spectator, nil)
+	OnRoomUpdate(room)
+	return spectator
+}
+
+func createPlayer(room *types.Room, requestedUsername string) *types.Player {
 	var username string
 	if requestedUsername != "" && room.IsUsernameAvailable(requestedUsername) {
 		username = requestedUsername
@@ -90,8 +147,6 @@ func JoinRoom(room *types.Room, requestedUsername string) *types.Player {
 		Mutex: &sync.Mutex{},
 	}
 	player.ResetInactivity()
-	room.AppendPlayer(player)
-	OnRoomUpdate(room)
 	return player
 }
 
@@ -111,6 +166,11 @@ func CalculateStats() GameStats {
 				stats.OnlinePlayerCount += 1
 			}
 		}
+		for _, spectator := range game.Spectators {
+			if spectator.Connection.IsConnected {
+				stats.OnlinePlayerCount += 1
+			}
+		}
 	}
 	return stats
 }
@@ -124,7 +184,7 @@ func UpdateGameState(room *types.Room, newState types.GameState) {
 }
 
 func SetCardDeck(room *types.Room, id int) bool {
-	if id < 0 || id > 1 {
+	if !decks.IsValidDeckId(id) {
 		return false
 	}
 	room.CardDeckId = id
@@ -132,13 +192,33 @@ func SetCardDeck(room *types.Room, id int) bool {
 	return true
 }
 
-func CreateCardDeckObj(room *types.Room) {
-	switch room.CardDeckId {
-	case 0:
-		room.CardDeck = &decks.Classic{}
-	case 1:
-		room.CardDeck = &decks.HexV1{}
+func SetGameOptions(room *types.Room, options types.GameOptions) bool {
+	if options.StartingHandSize < 1 || options.StartingHandSize > 20 {
+		return false
+	}
+	if options.LastCardPenalty < 0 || options.LastCardPenalty > 10 {
+		return false
+	}
+	if options.TargetScore < 0 || options.TargetScore > 10000 {
+		return false
 	}
+	room.GameOptions = options
+	OnRoomUpdate(room)
+	return true
+}
+
+func SetMoveTimeout(room *types.Room, moveTimeout int) bool {
+	if moveTimeout != 0 && (moveTimeout < 5*1000 || moveTimeout > 10*60*1000) {
+		return false
+	}
+	room.MoveTimeout = moveTimeout
+	room.ResetTurnTimer()
+	OnRoomUpdate(room)
+	return true
+}
+
+func CreateCardDeckObj(room *types.Room) {
+	room.CardDeck = decks.NewDeck(room.CardDeckId)
 }
 
 func BroadcastInRoom(room *types.Room, topic string, data interface{}) {
@@ -148,16 +228,102 @@ func BroadcastInRoom(room *types.Room, topic string, data interface{}) {
 		}
 		player.Connection.Socket.Emit(topic, data)
 	}
+	for _, spectator := range room.Spectators {
+		if !spectator.Connection.IsConnected || spectator.Connection.Socket == nil {
+			continue
+		}
+		spectator.Connection.Socket.Emit(topic, data)
+	}
 }
 
 func SendInitialData(room *types.Room, targetPlayer *types.Player) {
 	if targetPlayer.Connection.Socket == nil {
 		return
 	}
-	targetPlayer.Connection.Socket.Emit("OwnCards", types.BuildOwnCardsPacket(room, targetPlayer))
+	if !room.IsSpectator(targetPlayer) {
+		targetPlayer.Connection.Socket.Emit("OwnCards", types.BuildOwnCardsPacket(room, targetPlayer))
+	}
 	for _, player := range room.Players {
 		targetPlayer.Connection.Socket.Emit("PlayerState", types.BuildPlayerStatePacket(room, player))
 	}
+	if room.CardDeck != nil {
+		targetPlayer.Connection.Socket.Emit("DrawPenalty", types.BuildDrawPenaltyPacket(room))
+		targetPlayer.Connection.Socket.Emit("PendingChoice", types.BuildPendingChoicePacket(room))
+	}
+	if activePlayer := findActivePlayer(room); activePlayer != nil && room.MoveTimeout > 0 && !room.Paused {
+		targetPlayer.Connection.Socket.Emit("TurnTimer", types.BuildTurnTimerPacket(room, activePlayer))
+	}
+}
+
+func findActivePlayer(room *types.Room) *types.Player {
+	if room.GameState != types.StateRunning || room.CardDeck == nil {
+		return nil
+	}
+	for _, player := range room.Players {
+		if room.CardDeck.IsPlayerActive(player) {
+			return player
+		}
+	}
+	return nil
+}
+
+func restartTurnTimer(room *types.Room) {
+	room.ResetTurnTimer()
+	if room.MoveTimeout <= 0 {
+		return
+	}
+	activePlayer := findActivePlayer(room)
+	if activePlayer == nil {
+		return
+	}
+	BroadcastInRoom(room, "TurnTimer", types.BuildTurnTimerPacket(room, activePlayer))
+}
+
+func tickTurnTimer(room *types.Room, deltaTime int) {
+	if room.GameState != types.StateRunning || room.Paused || room.MoveTimeout <= 0 || room.NextRoundIn > 0 {
+		return
+	}
+	room.TurnTimeRemaining -= deltaTime
+	if room.TurnTimeRemaining > 0 {
+		return
+	}
+	activePlayer := findActivePlayer(room)
+	if activePlayer == nil {
+		room.ResetTurnTimer()
+		return
+	}
+
+	slog.Debug("Move timeout expired, applying default action", "username", activePlayer.Username, "playerId", activePlayer.PlayerId.Hex(), "roomId", room.RoomId.Hex())
+	activePlayer.Mutex.Lock()
+	defer activePlayer.Mutex.Unlock()
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	// The player may have finished their move while the locks were taken
+	if room.TurnTimeRemaining > 0 || verifyActivePlayer(room, activePlayer) != nil {
+		return
+	}
+	applyDefaultAction(room, activePlayer)
+}
+
+// applyDefaultAction lets the deck take the default action for the active
+// player. The caller has to hold the player's mutex and the room's ActionMutex
+// and verify the player is still active.
+func applyDefaultAction(room *types.Room, activePlayer *types.Player) {
+	LogEvent(room, types.EventTurnTimeout, activePlayer, nil)
+	handSize := len(activePlayer.Cards)
+	var kind types.ChoiceKind
+	if choice := room.CardDeck.GetPendingChoice(); choice != nil {
+		kind = choice.Kind
+	}
+	card := room.CardDeck.TimeoutTurn()
+	UpdateLastCardState(room, activePlayer)
+	if card != nil {
+		LogEvent(room, types.EventCardUpdated, activePlayer, types.EventDataCardUpdated{Card: card, Kind: kind})
+		OnPlayedCardUpdate(room, activePlayer, card)
+		return
+	}
+	LogEvent(room, types.EventCardsDrawn, activePlayer, types.EventDataCardsDrawn{Cards: drawnCards(activePlayer, handSize)})
+	UpdateAllPlayers(room)
 }
 
 func OnRoomUpdate(room *types.Room) {
@@ -169,10 +335,9 @@ func OnPlayerStateUpdate(room *types.Room, player *types.Player, skipDBUpdate bo
 	if !skipDBUpdate {
 		db.Conn.UpdateRoom(room)
 	}
-	if player.Connection.Socket == nil {
-		return
+	if player.Connection.Socket != nil {
+		player.Connection.Socket.Emit("OwnCards", types.BuildOwnCardsPacket(room, player))
 	}
-	player.Connection.Socket.Emit("OwnCards", types.BuildOwnCardsPacket(room, player))
 	BroadcastInRoom(room, "PlayerState", types.BuildPlayerStatePacket(room, player))
 }
 
@@ -181,6 +346,11 @@ func UpdateAllPlayers(room *types.Room) {
 	for _, player := range room.Players {
 		OnPlayerStateUpdate(room, player, true)
 	}
+	if room.CardDeck != nil {
+		BroadcastInRoom(room, "DrawPenalty", types.BuildDrawPenaltyPacket(room))
+		BroadcastInRoom(room, "PendingChoice", types.BuildPendingChoicePacket(room))
+	}
+	restartTurnTimer(room)
 }
 
 func OnPlayCard(room *types.Room, player *types.Player, cardIndex int, card types.Card) {
@@ -188,6 +358,11 @@ func OnPlayCard(room *types.Room, player *types.Player, cardIndex int, card type
 	UpdateAllPlayers(room)
 }
 
+func OnPlayCards(room *types.Room, player *types.Player, cardIndices []int, cards []types.Card) {
+	BroadcastInRoom(room, "CardsPlayed", types.BuildCardsPlayedPacket(player, cardIndices, cards))
+	UpdateAllPlayers(room)
+}
+
 func OnPlayedCardUpdate(room *types.Room, player *types.Player, card types.Card) {
 	BroadcastInRoom(room, "PlayedCardUpdate", types.BuildPlayedCardUpdatePacket(player, card))
 	UpdateAllPlayers(room)
@@ -197,12 +372,78 @@ func StartGame(room *types.Room) {
 	if room.GameState != types.StateLobby {
 		return
 	}
-	CreateCardDeckObj(room)
-	room.CardDeck.Init(room)
+	for _, player := range room.Players {
+		player.Score = 0
+	}
+	if isTeamMode(room) {
+		seatTeams(room)
+	}
+	room.Ranking = make([]bson.ObjectID, 0)
+	room.Round = 0
+	// Restart the random sequence so the game only depends on the seed and the players' actions
+	room.Random = utils.NewRandom(room.Seed)
+	if !dealRound(room) {
+		return
+	}
+	LogEvent(room, types.EventGameStarted, nil, buildRoundStartedEventData(room))
 	UpdateGameState(room, types.StateRunning)
 	UpdateAllPlayers(room)
 }
 
+// SetPaused pauses or resumes a running game. The turn timer keeps its
+// remaining time while the game is paused.
+func SetPaused(room *types.Room, paused bool) bool {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if room.GameState != types.StateRunning || room.Paused == paused {
+		return false
+	}
+	room.Paused = paused
+	if paused {
+		LogEvent(room, types.EventGamePaused, nil, nil)
+	} else {
+		LogEvent(room, types.EventGameResumed, nil, nil)
+	}
+	OnRoomUpdate(room)
+	if activePlayer := findActivePlayer(room); !paused && activePlayer != nil && room.MoveTimeout > 0 {
+		BroadcastInRoom(room, "TurnTimer", types.BuildTurnTimerPacket(room, activePlayer))
+	}
+	return true
+}
+
+// Rematch returns an ended room to the lobby with the same players, card deck
+// and options, so the host can start a new game
+func Rematch(room *types.Room) bool {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if room.GameState != types.StateEnded {
+		return false
+	}
+	// Player mutexes are taken before ActionMutex by the action handlers, so
+	// they can't be locked here without risking a deadlock
+	for _, player := range room.Players {
+		player.Cards = make([]types.Card, 0)
+		player.DeclaredLastCard = false
+		player.Score = 0
+	}
+	room.CardDeck = nil
+	room.Winner = nil
+	room.WinningTeam = 0
+	room.Ranking = nil
+	room.CatchablePlayer = nil
+	room.Round = 0
+	room.NextRoundIn = 0
+	room.Paused = false
+	// The old seed is revealed once the game has ended, so it must not predict
+	// the new one. The new seed is logged when the next game starts.
+	room.Seed = utils.NewSeed()
+	room.Random = utils.NewRandom(room.Seed)
+	LogEvent(room, types.EventRematch, nil, nil)
+	UpdateGameState(room, types.StateLobby)
+	UpdateAllPlayers(room)
+	return true
+}
+
 func TickRooms(deltaTime int) {
 	roomsMutex.Lock()
 	defer roomsMutex.Unlock()
@@ -214,19 +455,35 @@ func TickRooms(deltaTime int) {
 		room.PlayersMutex.Lock()
 		for j := 0; j < len(room.Players); j++ {
 			player := room.Players[j]
-			if player.Connection.IsConnected {
+			// Nobody is removed while the game is paused
+			if player.Connection.IsConnected || player.IsBot || room.Paused {
 				continue
 			}
 			if player.InactivityTimeout <= deltaTime {
 				slog.Debug("Removing player from room due to inactivity", "username", player.Username, "playerId", player.PlayerId.Hex(), "roomId", room.RoomId.Hex())
 				hasChanged = true
 				room.RemovePlayerUnsafe(*player)
+				LogEvent(room, types.EventPlayerLeft, player, nil)
 				j--
 			}
 			player.InactivityTimeout -= deltaTime
 		}
+		for j := 0; j < len(room.Spectators); j++ {
+			spectator := room.Spectators[j]
+			if spectator.Connection.IsConnected || room.Paused {
+				continue
+			}
+			if spectator.InactivityTimeout <= deltaTime {
+				slog.Debug("Removing spectator from room due to inactivity", "username", spectator.Username, "playerId", spectator.PlayerId.Hex(), "roomId", room.RoomId.Hex())
+				hasChanged = true
+				room.RemovePlayerUnsafe(*spectator)
+				LogEvent(room, types.EventPlayerLeft, spectator, nil)
+				j--
+			}
+			spectator.InactivityTimeout -= deltaTime
+		}
 
-		if len(room.Players) == 0 {
+		if !room.HasHumanPlayers() {
 			slog.Debug("Ending and unloading empty room", "roomId", room.RoomId.Hex())
 			UpdateGameState(room, types.StateEnded)
 			utils.RemoveSliceElement(&rooms, room)
@@ -237,5 +494,8 @@ func TickRooms(deltaTime int) {
 		if hasChanged {
 			OnRoomUpdate(room)
 		}
+		tickTurnTimer(room, deltaTime)
+		tickRoundIntermission(room, deltaTime)
+		tickBots(room, deltaTime)
 	}
 }
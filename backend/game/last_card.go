@@ -0,0 +1,77 @@
+package game
+
+import (
+	"log/slog"
+
+	"github.com/HexCardGames/HexDeck/types"
+)
+
+func resetLastCardState(room *types.Room) {
+	room.CatchablePlayer = nil
+	for _, player := range room.Players {
+		player.DeclaredLastCard = false
+	}
+}
+
+// UpdateLastCardState has to be called after a player performed a game action.
+// It closes the catch window of the previous player and opens one for the acting
+// player if they dropped to their last card without declaring it.
+func UpdateLastCardState(room *types.Room, player *types.Player) {
+	if room.CatchablePlayer != nil && *room.CatchablePlayer != player.PlayerId {
+		room.CatchablePlayer = nil
+	}
+	for _, roomPlayer := range room.Players {
+		if len(roomPlayer.Cards) > 2 {
+			roomPlayer.DeclaredLastCard = false
+		}
+	}
+	if room.GameOptions.LastCardPenalty > 0 && len(player.Cards) == 1 && !player.DeclaredLastCard {
+		room.CatchablePlayer = &player.PlayerId
+	}
+}
+
+func DeclareLastCard(room *types.Room, player *types.Player) *types.S2C_Status {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if status := verifyGameRunning(room); status != nil {
+		return status
+	}
+	if len(player.Cards) > 2 {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "cannot_declare_last_card",
+			Message:    "You can only declare your last card while holding two cards or less",
+		}
+	}
+	player.DeclaredLastCard = true
+	if room.CatchablePlayer != nil && *room.CatchablePlayer == player.PlayerId {
+		room.CatchablePlayer = nil
+	}
+	OnPlayerStateUpdate(room, player, false)
+	return nil
+}
+
+// CatchPlayer only requires the catcher's mutex; the target's hand is protected
+// by the room's ActionMutex like with any other draw penalty
+func CatchPlayer(room *types.Room, catcher *types.Player, target *types.Player) *types.S2C_Status {
+	room.ActionMutex.Lock()
+	defer room.ActionMutex.Unlock()
+	if status := verifyGameRunning(room); status != nil {
+		return status
+	}
+	if catcher == target || room.CatchablePlayer == nil || *room.CatchablePlayer != target.PlayerId {
+		return &types.S2C_Status{
+			IsError:    true,
+			StatusCode: "cannot_catch_player",
+			Message:    "This player can't be caught right now",
+		}
+	}
+	room.CatchablePlayer = nil
+	penalty := room.GameOptions.LastCardPenalty
+	room.CardDeck.DrawCards(target, penalty)
+	LogEvent(room, types.EventPlayerCaught, target, types.EventDataPlayerCaught{CaughtBy: catcher.PlayerId, Penalty: penalty})
+	slog.Debug("Player was caught not declaring their last card", "playerId", target.PlayerId.Hex(), "caughtBy", catcher.PlayerId.Hex(), "roomId", room.RoomId.Hex())
+	BroadcastInRoom(room, "PlayerCaught", types.BuildPlayerCaughtPacket(catcher, target, penalty))
+	UpdateAllPlayers(room)
+	return nil
+}
@@ -0,0 +1,156 @@
+package game
+
+import (
+	"log/slog"
+	"sort"
+
+	"github.com/HexCardGames/HexDeck/types"
+)
+
+// Time in milliseconds between the end of a round and dealing the next one
+const roundIntermission = 5 * 1000
+
+func isMatch(room *types.Room) bool {
+	return room.GameOptions.TargetScore > 0
+}
+
+func isRankedGame(room *types.Room) bool {
+	return room.GameOptions.PlayToFinish && !isMatch(room) && !isTeamMode(room)
+}
+
+// rankPlayer takes the finished player out of the turn order. The game ends
+// once a single player is left, who takes the last place.
+func rankPlayer(room *types.Room, player *types.Player) {
+	if room.HasFinished(player) {
+		return
+	}
+	room.Ranking = append(room.Ranking, player.PlayerId)
+	LogEvent(room, types.EventPlayerFinished, player, types.EventDataPlayerFinished{Place: len(room.Ranking)})
+
+	remainingPlayers := make([]*types.Player, 0, len(room.Players))
+	for _, roomPlayer := range room.Players {
+		if !room.HasFinished(roomPlayer) {
+			remainingPlayers = append(remainingPlayers, roomPlayer)
+		}
+	}
+	if len(remainingPlayers) > 1 {
+		OnRoomUpdate(room)
+		return
+	}
+	for _, roomPlayer := range remainingPlayers {
+		room.Ranking = append(room.Ranking, roomPlayer.PlayerId)
+	}
+	winner := player
+	for _, roomPlayer := range room.Players {
+		if roomPlayer.PlayerId == room.Ranking[0] {
+			winner = roomPlayer
+		}
+	}
+	endGame(room, winner)
+	UpdateGameState(room, types.StateEnded)
+}
+
+// endGame declares the player, and in team mode their team, as the winner
+func endGame(room *types.Room, player *types.Player) {
+	room.Winner = &player.PlayerId
+	if isTeamMode(room) {
+		room.WinningTeam = player.Team
+	}
+	LogEvent(room, types.EventGameEnded, player, types.EventDataGameEnded{Winner: room.Winner, WinningTeam: room.WinningTeam})
+}
+
+// OnPlayerFinished has to be called once a player emptied their hand. It ends
+// the game, or in match mode scores the round and schedules the next one until
+// the target score is reached. In team mode the player's whole team wins.
+func OnPlayerFinished(room *types.Room, player *types.Player) {
+	if isRankedGame(room) {
+		rankPlayer(room, player)
+		return
+	}
+	if !isMatch(room) {
+		endGame(room, player)
+		UpdateGameState(room, types.StateEnded)
+		return
+	}
+
+	points := 0
+	for _, roomPlayer := range room.Players {
+		// A team only scores the cards left in its opponents' hands
+		if isTeamMode(room) && isPartner(player, roomPlayer) {
+			continue
+		}
+		for _, card := range roomPlayer.Cards {
+			points += room.CardDeck.GetCardPoints(card)
+		}
+	}
+	player.Score += points
+	slog.Debug("Round ended", "roomId", room.RoomId.Hex(), "round", room.Round, "winner", player.PlayerId.Hex(), "points", points)
+	LogEvent(room, types.EventRoundEnded, player, types.EventDataRoundEnded{Round: room.Round, Points: points})
+
+	score := player.Score
+	if isTeamMode(room) {
+		score = getTeamScore(room, player.Team)
+	}
+	if score >= room.GameOptions.TargetScore {
+		endGame(room, player)
+		BroadcastInRoom(room, "RoundResult", buildRoundResultPacket(room, player, points))
+		UpdateGameState(room, types.StateEnded)
+		return
+	}
+	room.NextRoundIn = roundIntermission
+	BroadcastInRoom(room, "RoundResult", buildRoundResultPacket(room, player, points))
+	OnRoomUpdate(room)
+}
+
+func buildRoundResultPacket(room *types.Room, winner *types.Player, points int) types.S2C_RoundResult {
+	standings := make([]types.S2C_Standing, len(room.Players))
+	for i, player := range room.Players {
+		standings[i] = types.S2C_Standing{PlayerId: player.PlayerId, Score: player.Score, Team: player.Team}
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Score > standings[j].Score
+	})
+	return types.S2C_RoundResult{
+		Round:       room.Round,
+		Winner:      winner.PlayerId,
+		Points:      points,
+		TargetScore: room.GameOptions.TargetScore,
+		Standings:   standings,
+		MatchWinner: room.Winner,
+	}
+}
+
+// dealRound clears all hands and deals a fresh card deck
+func dealRound(room *types.Room) bool {
+	for _, player := range room.Players {
+		player.Mutex.Lock()
+		player.Cards = make([]types.Card, 0)
+		player.Mutex.Unlock()
+	}
+	CreateCardDeckObj(room)
+	if room.CardDeck == nil {
+		slog.Error("Cannot start game with unknown card deck", "roomId", room.RoomId.Hex(), "cardDeckId", room.CardDeckId)
+		return false
+	}
+	room.CardDeck.Init(room)
+	room.Round += 1
+	room.NextRoundIn = 0
+	resetLastCardState(room)
+	return true
+}
+
+func tickRoundIntermission(room *types.Room, deltaTime int) {
+	if room.GameState != types.StateRunning || room.Paused || room.NextRoundIn <= 0 {
+		return
+	}
+	room.NextRoundIn -= deltaTime
+	if room.NextRoundIn > 0 {
+		return
+	}
+	if !dealRound(room) {
+		return
+	}
+	LogEvent(room, types.EventRoundStarted, nil, buildRoundStartedEventData(room))
+	OnRoomUpdate(room)
+	UpdateAllPlayers(room)
+}
@@ -0,0 +1,81 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/HexCardGames/HexDeck/types"
+)
+
+// Highest team number a player can join
+const maxTeams = 4
+
+func isTeamMode(room *types.Room) bool {
+	return room.GameOptions.TeamMode
+}
+
+func IsValidTeam(team int) bool {
+	return team >= 0 && team <= maxTeams
+}
+
+// getTeams groups the players by team, ordered by team number
+func getTeams(room *types.Room) [][]*types.Player {
+	teamMap := make(map[int][]*types.Player)
+	for _, player := range room.Players {
+		teamMap[player.Team] = append(teamMap[player.Team], player)
+	}
+	teamNumbers := make([]int, 0, len(teamMap))
+	for team := range teamMap {
+		teamNumbers = append(teamNumbers, team)
+	}
+	sort.Ints(teamNumbers)
+	teams := make([][]*types.Player, len(teamNumbers))
+	for i, team := range teamNumbers {
+		teams[i] = teamMap[team]
+	}
+	return teams
+}
+
+// ValidateTeams checks that every player joined a team and that there are at
+// least two teams of equal size
+func ValidateTeams(room *types.Room) bool {
+	teams := getTeams(room)
+	if len(teams) < 2 {
+		return false
+	}
+	for _, team := range teams {
+		if team[0].Team == 0 || len(team) != len(teams[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// seatTeams reorders the players so the teams take turns alternately
+func seatTeams(room *types.Room) {
+	room.PlayersMutex.Lock()
+	defer room.PlayersMutex.Unlock()
+	teams := getTeams(room)
+	players := make([]*types.Player, 0, len(room.Players))
+	for i := 0; len(players) < len(room.Players); i++ {
+		for _, team := range teams {
+			if i < len(team) {
+				players = append(players, team[i])
+			}
+		}
+	}
+	room.Players = players
+}
+
+func getTeamScore(room *types.Room, team int) int {
+	score := 0
+	for _, player := range room.Players {
+		if player.Team == team {
+			score += player.Score
+		}
+	}
+	return score
+}
+
+func isPartner(player *types.Player, target *types.Player) bool {
+	return player != target && player.Team != 0 && player.Team == target.Team
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/HexCardGames/HexDeck/api"
 	"github.com/HexCardGames/HexDeck/db"
+	"github.com/HexCardGames/HexDeck/decks"
 	"github.com/HexCardGames/HexDeck/game"
 	"github.com/HexCardGames/HexDeck/utils"
 	"github.com/gin-gonic/gin"
@@ -35,6 +36,8 @@ func main() {
 		slog.Error("Initializing MongoDB database failed")
 		return
 	}
+	// Custom decks have to be registered before rooms using them are restored
+	decks.LoadCustomDecks(utils.Getenv("CUSTOM_DECKS_DIR", "custom_decks"))
 	game.LoadRooms()
 
 	roomTicker := time.NewTicker(1 * time.Second)
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type EventType string
+
+const (
+	EventPlayerJoined       EventType = "player_joined"
+	EventSpectatorJoined    EventType = "spectator_joined"
+	EventPlayerLeft         EventType = "player_left"
+	EventPlayerKicked       EventType = "player_kicked"
+	EventGameStarted        EventType = "game_started"
+	EventRoundStarted       EventType = "round_started"
+	EventCardPlayed         EventType = "card_played"
+	EventCardsPlayed        EventType = "cards_played"
+	EventPlayerFinished     EventType = "player_finished"
+	EventCardPassed         EventType = "card_passed"
+	EventCardsDrawn         EventType = "cards_drawn"
+	EventCardUpdated        EventType = "card_updated"
+	EventTurnPassed         EventType = "turn_passed"
+	EventTurnTimeout        EventType = "turn_timeout"
+	EventPlayerCaught       EventType = "player_caught"
+	EventDrawFourChallenged EventType = "draw_four_challenged"
+	EventRoundEnded         EventType = "round_ended"
+	EventGamePaused         EventType = "game_paused"
+	EventGameResumed        EventType = "game_resumed"
+	EventRematch            EventType = "rematch"
+	EventGameEnded          EventType = "game_ended"
+)
+
+// GameEvent is a single entry of a room's event log. Sequence is increasing
+// per room and defines the order in which the events happened.
+type GameEvent struct {
+	RoomId    bson.ObjectID
+	Sequence  int
+	Timestamp time.Time
+	Type      EventType
+	PlayerId  *bson.ObjectID
+	Data      interface{}
+}
+
+type EventDataHand struct {
+	PlayerId bson.ObjectID
+	Cards    []Card
+}
+type EventDataRoundStarted struct {
+	Round       int
+	Seed        int64
+	CardDeckId  int
+	GameOptions GameOptions
+	Hands       []EventDataHand
+}
+type EventDataCardPlayed struct {
+	Card      Card
+	CardIndex int
+	JumpIn    bool
+}
+type EventDataCardsPlayed struct {
+	Cards       []Card
+	CardIndices []int
+}
+type EventDataCardPassed struct {
+	Card      Card
+	CardIndex int
+	PassedTo  bson.ObjectID
+}
+type EventDataCardsDrawn struct {
+	Cards []Card
+}
+type EventDataCardUpdated struct {
+	Card   Card
+	Kind   ChoiceKind
+	Answer string
+}
+type EventDataPlayerKicked struct {
+	KickedBy bson.ObjectID
+}
+type EventDataPlayerCaught struct {
+	CaughtBy bson.ObjectID
+	Penalty  int
+}
+type EventDataChallenge struct {
+	ChallengedBy bson.ObjectID
+	Successful   bool
+	Penalty      int
+}
+type EventDataRoundEnded struct {
+	Round  int
+	Points int
+}
+type EventDataPlayerFinished struct {
+	Place int
+}
+type EventDataGameEnded struct {
+	Winner      *bson.ObjectID
+	WinningTeam int
+}
@@ -1,8 +1,10 @@
 package types
 
 import (
+	"errors"
 	"sync"
 
+	"github.com/HexCardGames/HexDeck/utils"
 	"github.com/zishang520/socket.io/v2/socket"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -15,23 +17,111 @@ type WebsocketConnection struct {
 type Card interface {
 }
 
+var (
+	// ErrDrawNotAllowed is returned when the active player can't draw a card right now
+	ErrDrawNotAllowed = errors.New("drawing a card is not allowed right now")
+	// ErrDeckExhausted is returned when no card is left to draw; the turn still passes
+	ErrDeckExhausted = errors.New("no cards are left to draw")
+)
+
+type ChoiceKind string
+
+const (
+	ChoiceColor  ChoiceKind = "color"
+	ChoicePlayer ChoiceKind = "player"
+	ChoiceCard   ChoiceKind = "card"
+	ChoiceYesNo  ChoiceKind = "yes_no"
+)
+
+// PendingChoice is a decision a specific player has to make before the game
+// can continue. Options holds every valid answer: color names, player IDs in
+// hex, card indices or "yes" and "no".
+type PendingChoice struct {
+	Kind     ChoiceKind
+	PlayerId bson.ObjectID
+	Options  []string
+}
+
+func (choice *PendingChoice) HasOption(answer string) bool {
+	for _, option := range choice.Options {
+		if option == answer {
+			return true
+		}
+	}
+	return false
+}
+
 type CardDeck interface {
 	Init(*Room)
 	SetRoom(*Room)
 	IsEmpty() bool
-	DrawCard() Card
+	DrawCard() (Card, error)
+	DrawCards(*Player, int)
+	// PassTurn ends the turn of the active player after they drew a playable card
+	PassTurn() bool
+	// SkipTurn ends the turn of the active player without a move. It fails
+	// while a choice or draw penalty is pending.
+	SkipTurn() bool
 	CanPlay(Card) bool
 	PlayCard(Card) bool
 	GetTopCard() Card
-	UpdatePlayedCard(interface{}) Card
+	// GetPendingChoice returns the decision the game is waiting for, or nil
+	GetPendingChoice() *PendingChoice
+	// ResolveChoice answers the pending choice on behalf of the player and
+	// returns the updated top card, or nil if the answer was rejected
+	ResolveChoice(*Player, string) Card
 	IsPlayerActive(*Player) bool
+	GetPendingDraw() int
+	// GetCardPoints returns the score value of a card left in an opponent's hand
+	GetCardPoints(Card) int
+	// TimeoutTurn applies the default action for the active player after their
+	// move timeout expired and returns the updated card if a pending choice
+	// was resolved
+	TimeoutTurn() Card
+}
+
+// ChallengeResult describes the outcome of a Wild Draw Four challenge. Hand is
+// the hand the challenged player held when they played the card.
+type ChallengeResult struct {
+	Challenger    *Player
+	Challenged    *Player
+	Successful    bool
+	PreviousColor string
+	Hand          []Card
+	Penalty       int
+}
+
+// MultiCardDeck is implemented by decks that allow playing several cards in a
+// single move. The cards are played in the given order.
+type MultiCardDeck interface {
+	CanPlayCards(cards []Card) bool
+	PlayCards(cards []Card) bool
+}
+
+// JumpInDeck is implemented by decks that let a player jump in out of turn with
+// a card identical to the top card. The turn continues from that player.
+type JumpInDeck interface {
+	CanJumpIn(player *Player, card Card) bool
+	JumpIn(player *Player, card Card) bool
+}
+
+// DrawFourChallenger is implemented by decks that support challenging a Wild
+// Draw Four. ChallengeDrawFour returns nil if no challenge is possible.
+type DrawFourChallenger interface {
+	ChallengeDrawFour() *ChallengeResult
 }
 
 type Player struct {
-	PlayerId          bson.ObjectID
-	SessionToken      string
-	Username          string
-	Permissions       int
+	PlayerId         bson.ObjectID
+	SessionToken     string
+	Username         string
+	Permissions      int
+	DeclaredLastCard bool
+	Score            int
+	// Team of the player in team mode, 0 if unassigned
+	Team int
+	// Bots have no session and are played by the server
+	IsBot             bool
 	Cards             []Card              `json:"-"`
 	Connection        WebsocketConnection `bson:"-" json:"-"`
 	InactivityTimeout int                 `bson:"-" json:"-"`
@@ -65,24 +155,132 @@ const (
 type RoomPermission int
 
 const (
-	PermissionHost RoomPermission = 0
+	// Co-hosts hold every other permission and may update other players
+	PermissionHost RoomPermission = iota
+	PermissionKick
+	PermissionChangeDeck
+	PermissionChangeOptions
+	PermissionStartGame
+	permissionCount
 )
 
+// AllPermissions is the permission mask held by the room owner and co-hosts
+const AllPermissions int = 1<<permissionCount - 1
+
+type PermissionInfo struct {
+	Permission RoomPermission
+	Name       string
+	// Socket events that require this permission
+	Events []string
+}
+
+// PermissionMatrix lists every permission and the actions it allows. The
+// owner and co-hosts implicitly hold all of them.
+var PermissionMatrix = []PermissionInfo{
+	{PermissionHost, "co_host", []string{"UpdatePlayer", "AddBot", "PauseGame", "ResumeGame"}},
+	{PermissionKick, "kick", []string{"KickPlayer"}},
+	{PermissionChangeDeck, "change_deck", []string{"SetCardDeck"}},
+	{PermissionChangeOptions, "change_options", []string{"SetMoveTimeout", "UpdateGameOptions"}},
+	{PermissionStartGame, "start_game", []string{"StartGame", "Rematch"}},
+}
+
+func IsValidPermissionMask(permissions int) bool {
+	return permissions&^AllPermissions == 0
+}
+
 type GameOptions struct {
+	// Number of cards each player is dealt when the game starts
+	StartingHandSize int
+	// Players can't draw a card while they hold a playable card
+	MustPlayIfAble bool
+	// Drawing continues until a playable card is drawn
+	DrawUntilPlayable bool
+	// Wildcards can be played on top of another wildcard
+	AllowWildOnWild bool
+	// Draw cards can be answered with a matching draw card, adding up the penalty
+	DrawStacking bool
+	// Cards drawn by a player caught not declaring their last card, 0 disables catching
+	LastCardPenalty int
+	// Score a player needs to win the match, 0 ends the game after a single round
+	TargetScore int
+	// The victim of a Wild Draw Four can challenge whether it was played legally
+	DrawFourChallenge bool
+	// Players holding a card identical to the top card may play it out of turn
+	JumpIn bool
+	// Classic only: playing a 7 swaps hands with a chosen player, a 0 passes all hands on
+	SevenZero bool
+	// HexV1 only: draw from a finite, shuffled deck instead of generating random cards
+	FiniteHexDeck bool
+	// Allow playing several cards at once, like all cards with the same number
+	MultiCardPlays bool
+	// Players are seated alternately by team and a team wins once any member emptied their hand
+	TeamMode bool
+	// Keep playing after the first player finished until only one is left,
+	// which ranks all players. Ignored in match and team mode.
+	PlayToFinish bool
+	// Team mode only: the active player may pass a card to a teammate instead of playing
+	PassToPartner bool
+}
+
+func DefaultGameOptions() GameOptions {
+	return GameOptions{
+		StartingHandSize:  7,
+		MustPlayIfAble:    false,
+		DrawUntilPlayable: false,
+		AllowWildOnWild:   true,
+		DrawStacking:      false,
+		LastCardPenalty:   0,
+		TargetScore:       0,
+		DrawFourChallenge: false,
+		JumpIn:            false,
+		SevenZero:         false,
+		FiniteHexDeck:     false,
+		MultiCardPlays:    false,
+		TeamMode:          false,
+		PlayToFinish:      false,
+		PassToPartner:     false,
+	}
 }
 
 type Room struct {
-	RoomId       bson.ObjectID `bson:"_id"`
-	JoinCode     string
-	GameState    GameState
-	GameOptions  GameOptions
-	CardDeckId   int
-	CardDeck     CardDeck
-	Players      []*Player
+	RoomId      bson.ObjectID `bson:"_id"`
+	JoinCode    string
+	GameState   GameState
+	GameOptions GameOptions
+	CardDeckId  int
+	CardDeck    CardDeck
+	Players     []*Player
+	// Spectators receive all public room updates but take no part in the game
+	Spectators   []*Player
 	PlayersMutex *sync.Mutex `bson:"-"`
-	OwnerId      bson.ObjectID
-	MoveTimeout  int
-	Winner       *bson.ObjectID
+	// Serializes game actions, so a player jumping in can't race the active player
+	ActionMutex *sync.Mutex `bson:"-"`
+	OwnerId     bson.ObjectID
+	// Seed of the room's random number generator, used for all shuffles and card generation
+	Seed   int64
+	Random *utils.Random `json:"-"`
+	// Time in milliseconds a player has for each move, 0 disables the timer
+	MoveTimeout       int
+	TurnTimeRemaining int `bson:"-" json:"-"`
+	BotTimeRemaining  int `bson:"-" json:"-"`
+	// Player who dropped to their last card without declaring it and can still be caught
+	CatchablePlayer *bson.ObjectID
+	Round           int
+	// Time in milliseconds until the next round of a match is dealt, 0 if none is scheduled
+	NextRoundIn int
+	// While paused no game actions are accepted and all timers stand still
+	Paused bool
+	// Sequence number of the next entry in the room's event log
+	EventSequence int
+	// Player who emptied their hand first and, in team mode, their team
+	Winner      *bson.ObjectID
+	WinningTeam int
+	// Players in the order they finished, only used with PlayToFinish
+	Ranking []bson.ObjectID
+}
+
+func (room *Room) ResetTurnTimer() {
+	room.TurnTimeRemaining = room.MoveTimeout
 }
 
 func (room *Room) AppendPlayer(player *Player) {
@@ -91,6 +289,33 @@ func (room *Room) AppendPlayer(player *Player) {
 	room.Players = append(room.Players, player)
 }
 
+func (room *Room) AppendSpectator(spectator *Player) {
+	room.PlayersMutex.Lock()
+	defer room.PlayersMutex.Unlock()
+	room.Spectators = append(room.Spectators, spectator)
+}
+
+func (room *Room) IsSpectator(target *Player) bool {
+	for _, spectator := range room.Spectators {
+		if spectator == target {
+			return true
+		}
+	}
+	return false
+}
+
+func (room *Room) FindSpectator(playerId bson.ObjectID) *Player {
+	room.PlayersMutex.Lock()
+	defer room.PlayersMutex.Unlock()
+
+	for _, spectator := range room.Spectators {
+		if spectator.PlayerId == playerId {
+			return spectator
+		}
+	}
+	return nil
+}
+
 func (room *Room) RemovePlayer(target Player) bool {
 	room.PlayersMutex.Lock()
 	defer room.PlayersMutex.Unlock()
@@ -112,6 +337,23 @@ func (room *Room) FindPlayer(playerId bson.ObjectID) *Player {
 	return nil
 }
 
+func (room *Room) IsOwner(player *Player) bool {
+	return room.OwnerId == player.PlayerId
+}
+
+// GetPermissions returns the permission mask the player effectively holds,
+// including the permissions implied by being owner or co-host
+func (room *Room) GetPermissions(player *Player) int {
+	if room.IsOwner(player) || player.HasPermissionBit(PermissionHost) {
+		return AllPermissions
+	}
+	return player.Permissions & AllPermissions
+}
+
+func (room *Room) HasPermission(player *Player, permission RoomPermission) bool {
+	return room.GetPermissions(player)&(1<<permission) > 0
+}
+
 func (room *Room) RemovePlayerUnsafe(target Player) bool {
 	foundHost := false
 	foundPlayer := false
@@ -128,19 +370,67 @@ func (room *Room) RemovePlayerUnsafe(target Player) bool {
 		}
 	}
 	if !foundPlayer {
-		return false
+		return room.removeSpectatorUnsafe(target)
 	}
-	if !foundHost && len(room.Players) > 0 {
-		room.Players[0].SetPermissionBit(PermissionHost)
+	if room.OwnerId == target.PlayerId {
+		room.OwnerId = bson.ObjectID{}
+	}
+	for _, player := range room.Players {
+		if player.IsBot {
+			continue
+		}
+		if !foundHost {
+			player.SetPermissionBit(PermissionHost)
+			foundHost = true
+		}
+		// Ownership passes on to the first remaining co-host
+		if room.OwnerId.IsZero() && player.HasPermissionBit(PermissionHost) {
+			room.OwnerId = player.PlayerId
+		}
 	}
 	return true
 }
 
+// HasFinished reports whether the player already emptied their hand and left
+// the turn order
+func (room *Room) HasFinished(target *Player) bool {
+	for _, playerId := range room.Ranking {
+		if playerId == target.PlayerId {
+			return true
+		}
+	}
+	return false
+}
+
+func (room *Room) HasHumanPlayers() bool {
+	for _, player := range room.Players {
+		if !player.IsBot {
+			return true
+		}
+	}
+	return false
+}
+
+func (room *Room) removeSpectatorUnsafe(target Player) bool {
+	for i, spectator := range room.Spectators {
+		if spectator.PlayerId == target.PlayerId {
+			room.Spectators = append(room.Spectators[:i], room.Spectators[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func (room *Room) IsUsernameAvailable(username string) bool {
 	for _, player := range room.Players {
 		if player.Username == username {
 			return false
 		}
 	}
+	for _, spectator := range room.Spectators {
+		if spectator.Username == username {
+			return false
+		}
+	}
 	return true
 }
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
@@ -13,17 +15,31 @@ type S2C_PlayerInfo struct {
 	PlayerId    bson.ObjectID
 	Username    string
 	Permissions int
-	IsConnected bool
+	// Permissions including those implied by being owner or co-host
+	EffectivePermissions int
+	IsConnected          bool
+	IsBot                bool
+	Score                int
+	Team                 int
 }
 type S2C_RoomInfo struct {
 	RoomId      bson.ObjectID `bson:"_id"`
 	JoinCode    string
+	OwnerId     bson.ObjectID
 	GameState   GameState
 	GameOptions GameOptions
 	TopCard     Card
 	CardDeckId  int
+	MoveTimeout int
+	// Only revealed once the game has ended, as it allows replaying every shuffle
+	Seed        *int64
+	Round       int
+	Paused      bool
 	Winner      *bson.ObjectID
+	WinningTeam int
+	Ranking     []bson.ObjectID
 	Players     []S2C_PlayerInfo
+	Spectators  []S2C_PlayerInfo
 }
 type S2C_Card struct {
 	CanPlay bool
@@ -33,59 +49,160 @@ type S2C_OwnCards struct {
 	Cards []S2C_Card
 }
 type S2C_PlayerState struct {
-	PlayerId bson.ObjectID
-	NumCards int
-	Active   bool
+	PlayerId         bson.ObjectID
+	NumCards         int
+	Active           bool
+	DeclaredLastCard bool
 }
 type S2C_CardPlayed struct {
 	Card      Card
 	CardIndex int
 	PlayedBy  bson.ObjectID
 }
+type S2C_CardsPlayed struct {
+	Cards       []Card
+	CardIndices []int
+	PlayedBy    bson.ObjectID
+}
 type S2C_PlayedCardUpdate struct {
 	UpdatedBy bson.ObjectID
 	Card      Card
 }
 
+type S2C_Standing struct {
+	PlayerId bson.ObjectID
+	Score    int
+	Team     int
+}
+type S2C_RoundResult struct {
+	Round       int
+	Winner      bson.ObjectID
+	Points      int
+	TargetScore int
+	Standings   []S2C_Standing
+	MatchWinner *bson.ObjectID
+}
+type S2C_ChallengeResult struct {
+	ChallengedBy  bson.ObjectID
+	PlayerId      bson.ObjectID
+	Successful    bool
+	PreviousColor string
+	Hand          []Card
+	Penalty       int
+}
+type S2C_PlayerCaught struct {
+	PlayerId bson.ObjectID
+	CaughtBy bson.ObjectID
+	Penalty  int
+}
+type S2C_PendingChoice struct {
+	Pending  bool
+	Kind     ChoiceKind
+	PlayerId bson.ObjectID
+	Options  []string
+}
+type S2C_DrawPenalty struct {
+	Amount int
+}
+type S2C_TurnTimer struct {
+	PlayerId    bson.ObjectID
+	Deadline    int64
+	MoveTimeout int
+}
+
 type C2S_SetCardDeck struct {
 	CardDeckId int
 }
+type C2S_SetMoveTimeout struct {
+	MoveTimeout int
+}
+type C2S_UpdateGameOptions struct {
+	GameOptions GameOptions
+}
 type C2S_UpdatePlayer struct {
 	PlayerId    bson.ObjectID
 	Username    *string
 	Permissions *int
+	Team        *int
 }
 type C2S_KickPlayer struct {
 	PlayerId bson.ObjectID
 }
+type C2S_CatchPlayer struct {
+	PlayerId bson.ObjectID
+}
 type C2S_PlayCard struct {
 	CardIndex *int
 	CardData  interface{}
 }
+type C2S_PassCard struct {
+	CardIndex *int
+	PlayerId  bson.ObjectID
+}
+type C2S_PlayCards struct {
+	CardIndices []int
+}
 type C2S_UpdatePlayedCard struct {
 	CardData interface{}
 }
 
-func BuildRoomInfoPacket(room *Room) S2C_RoomInfo {
-	players := make([]S2C_PlayerInfo, len(room.Players))
-	for i, player := range room.Players {
-		players[i] = S2C_PlayerInfo{
-			PlayerId:    player.PlayerId,
-			Username:    player.Username,
-			Permissions: player.Permissions,
-			IsConnected: player.Connection.IsConnected,
+// Answer converts the card data sent by older clients to a choice answer
+func (request C2S_UpdatePlayedCard) Answer() string {
+	cardData, ok := request.CardData.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if color, ok := cardData["Color"].(string); ok {
+		return color
+	}
+	if playerId, ok := cardData["PlayerId"].(string); ok {
+		return playerId
+	}
+	return ""
+}
+
+type C2S_ResolveChoice struct {
+	Answer string
+}
+
+func buildPlayerInfoList(room *Room, players []*Player) []S2C_PlayerInfo {
+	playerInfos := make([]S2C_PlayerInfo, len(players))
+	for i, player := range players {
+		playerInfos[i] = S2C_PlayerInfo{
+			PlayerId:             player.PlayerId,
+			Username:             player.Username,
+			Permissions:          player.Permissions,
+			EffectivePermissions: room.GetPermissions(player),
+			IsConnected:          player.Connection.IsConnected,
+			IsBot:                player.IsBot,
+			Score:                player.Score,
+			Team:                 player.Team,
 		}
 	}
+	return playerInfos
+}
+
+func BuildRoomInfoPacket(room *Room) S2C_RoomInfo {
 	roomInfo := S2C_RoomInfo{
 		RoomId:      room.RoomId,
 		JoinCode:    room.JoinCode,
+		OwnerId:     room.OwnerId,
 		GameState:   room.GameState,
 		CardDeckId:  room.CardDeckId,
+		MoveTimeout: room.MoveTimeout,
+		Round:       room.Round,
+		Paused:      room.Paused,
 		GameOptions: room.GameOptions,
 		Winner:      room.Winner,
-		Players:     players,
+		WinningTeam: room.WinningTeam,
+		Ranking:     room.Ranking,
+		Players:     buildPlayerInfoList(room, room.Players),
+		Spectators:  buildPlayerInfoList(room, room.Spectators),
 	}
 
+	if room.GameState == StateEnded {
+		roomInfo.Seed = &room.Seed
+	}
 	if room.CardDeck != nil {
 		roomInfo.TopCard = room.CardDeck.GetTopCard()
 	}
@@ -93,11 +210,22 @@ func BuildRoomInfoPacket(room *Room) S2C_RoomInfo {
 }
 
 func BuildOwnCardsPacket(room *Room, player *Player) S2C_OwnCards {
+	if room.CardDeck == nil {
+		return S2C_OwnCards{Cards: make([]S2C_Card, 0)}
+	}
+	canPlay := room.CardDeck.CanPlay
+	// Players waiting for their turn can only play cards they may jump in with
+	jumpInDeck, ok := room.CardDeck.(JumpInDeck)
+	if ok && room.GameOptions.JumpIn && !room.CardDeck.IsPlayerActive(player) {
+		canPlay = func(card Card) bool {
+			return jumpInDeck.CanJumpIn(player, card)
+		}
+	}
 	cards := make([]S2C_Card, len(player.Cards))
 	for i, card := range player.Cards {
 		cards[i] = S2C_Card{
 			Card:    card,
-			CanPlay: room.CardDeck.CanPlay(card),
+			CanPlay: canPlay(card),
 		}
 	}
 	return S2C_OwnCards{
@@ -110,11 +238,41 @@ func BuildPlayerStatePacket(room *Room, player *Player) S2C_PlayerState {
 	if room.CardDeck != nil && room.CardDeck.IsPlayerActive(player) {
 		isActivePlayer = true
 	}
-	return S2C_PlayerState{PlayerId: player.PlayerId, NumCards: len(player.Cards), Active: isActivePlayer}
+	return S2C_PlayerState{PlayerId: player.PlayerId, NumCards: len(player.Cards), Active: isActivePlayer, DeclaredLastCard: player.DeclaredLastCard}
 }
 func BuildCardPlayedPacket(player *Player, cardIndex int, card Card) S2C_CardPlayed {
 	return S2C_CardPlayed{Card: card, CardIndex: cardIndex, PlayedBy: player.PlayerId}
 }
+func BuildCardsPlayedPacket(player *Player, cardIndices []int, cards []Card) S2C_CardsPlayed {
+	return S2C_CardsPlayed{Cards: cards, CardIndices: cardIndices, PlayedBy: player.PlayerId}
+}
+func BuildChallengeResultPacket(result *ChallengeResult) S2C_ChallengeResult {
+	return S2C_ChallengeResult{
+		ChallengedBy:  result.Challenger.PlayerId,
+		PlayerId:      result.Challenged.PlayerId,
+		Successful:    result.Successful,
+		PreviousColor: result.PreviousColor,
+		Hand:          result.Hand,
+		Penalty:       result.Penalty,
+	}
+}
+func BuildPlayerCaughtPacket(catcher *Player, target *Player, penalty int) S2C_PlayerCaught {
+	return S2C_PlayerCaught{PlayerId: target.PlayerId, CaughtBy: catcher.PlayerId, Penalty: penalty}
+}
+func BuildDrawPenaltyPacket(room *Room) S2C_DrawPenalty {
+	return S2C_DrawPenalty{Amount: room.CardDeck.GetPendingDraw()}
+}
+func BuildPendingChoicePacket(room *Room) S2C_PendingChoice {
+	choice := room.CardDeck.GetPendingChoice()
+	if choice == nil {
+		return S2C_PendingChoice{Pending: false, Options: []string{}}
+	}
+	return S2C_PendingChoice{Pending: true, Kind: choice.Kind, PlayerId: choice.PlayerId, Options: choice.Options}
+}
+func BuildTurnTimerPacket(room *Room, player *Player) S2C_TurnTimer {
+	deadline := time.Now().Add(time.Duration(room.TurnTimeRemaining) * time.Millisecond)
+	return S2C_TurnTimer{PlayerId: player.PlayerId, Deadline: deadline.UnixMilli(), MoveTimeout: room.MoveTimeout}
+}
 func BuildPlayedCardUpdatePacket(player *Player, card Card) S2C_PlayedCardUpdate {
 	return S2C_PlayedCardUpdate{UpdatedBy: player.PlayerId, Card: card}
 }
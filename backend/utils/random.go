@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"math/rand/v2"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Random is a seeded random number generator. Its state is stored as binary
+// data in the database, so a restored room continues the same random sequence.
+type Random struct {
+	source *rand.PCG
+	rand   *rand.Rand
+}
+
+func NewRandom(seed int64) *Random {
+	source := rand.NewPCG(uint64(seed), uint64(seed))
+	return &Random{source: source, rand: rand.New(source)}
+}
+
+// NewSeed returns a random seed that can be passed to NewRandom
+func NewSeed() int64 {
+	return rand.Int64()
+}
+
+func (random *Random) IntN(n int) int {
+	return random.rand.IntN(n)
+}
+
+func (random *Random) Int64() int64 {
+	return random.rand.Int64()
+}
+
+func (random *Random) MarshalBSONValue() (byte, []byte, error) {
+	state, err := random.source.MarshalBinary()
+	if err != nil {
+		return 0, nil, err
+	}
+	typ, data, err := bson.MarshalValue(state)
+	return byte(typ), data, err
+}
+
+func (random *Random) UnmarshalBSONValue(typ byte, data []byte) error {
+	var state []byte
+	if err := bson.UnmarshalValue(bson.Type(typ), data, &state); err != nil {
+		return err
+	}
+	random.source = &rand.PCG{}
+	if err := random.source.UnmarshalBinary(state); err != nil {
+		return err
+	}
+	random.rand = rand.New(random.source)
+	return nil
+}
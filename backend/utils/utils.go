@@ -2,8 +2,6 @@ package utils
 
 import (
 	"os"
-
-	"golang.org/x/exp/rand"
 )
 
 func Getenv(key string, fallback string) string {
@@ -25,10 +23,10 @@ func RemoveSliceElement[T comparable](slice *([]T), target T) bool {
 	return false
 }
 
-func ShuffleSlice[T any](slice *([]T)) {
+func ShuffleSlice[T any](slice *([]T), random *Random) {
 	length := len(*slice)
 	for i := 0; i < length; i++ {
-		j := rand.Intn(i + 1)
+		j := random.IntN(i + 1)
 		(*slice)[i], (*slice)[j] = (*slice)[j], (*slice)[i]
 	}
 }